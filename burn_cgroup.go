@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// burnerSubcommand starts the binary as a child burner that takes its load
+// from stdin instead of running the control loop
+const burnerSubcommand = "burner"
+
+// Names of the cgroups created under goburn's own cgroup. cgroup v2 only
+// delegates controllers from cgroups without processes, so the control loop
+// moves into its own leaf next to the burn cgroup.
+const (
+	burnCgroupControl = "control"
+	burnCgroupBurn    = "burn"
+)
+
+// burnChildMemoryHeadroomMB covers the Go runtime of the child on top of the
+// memory it is asked to hold
+const burnChildMemoryHeadroomMB = 64
+
+// burnChild is a burner process running in the burn cgroup
+type burnChild struct {
+	dir   string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	mu         sync.Mutex
+	cpu        selfUsageTracker
+	highEvents uint64
+}
+
+// burnFeedback is what the kernel let the burn cgroup use since the last read
+type burnFeedback struct {
+	CPUMillicores    float64
+	CPUKnown         bool
+	MemoryBytes      int64
+	MemoryHighEvents uint64
+}
+
+// setupBurnCgroup creates the control and burn cgroups under parent, moves
+// every process of parent into the control leaf and returns the burn cgroup
+func setupBurnCgroup(parent string, cpuWeight int, memoryHighMB int64) (string, error) {
+	// After a restart goburn already lives in the control leaf
+	if filepath.Base(parent) == burnCgroupControl {
+		parent = filepath.Dir(parent)
+	}
+
+	control := filepath.Join(parent, burnCgroupControl)
+	if err := os.MkdirAll(control, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create control cgroup: %v", err)
+	}
+
+	procs, err := os.ReadFile(filepath.Join(parent, "cgroup.procs"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read cgroup.procs: %v", err)
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		if err := writeCgroupValue(control, "cgroup.procs", pid); err != nil {
+			return "", err
+		}
+	}
+
+	if err := writeCgroupValue(parent, "cgroup.subtree_control", "+cpu +memory"); err != nil {
+		return "", err
+	}
+
+	burn := filepath.Join(parent, burnCgroupBurn)
+	if err := os.MkdirAll(burn, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create burn cgroup: %v", err)
+	}
+	if err := writeCgroupValue(burn, "cpu.weight", strconv.Itoa(cpuWeight)); err != nil {
+		return "", err
+	}
+	memoryHigh := "max"
+	if memoryHighMB > 0 {
+		memoryHigh = strconv.FormatInt(memoryHighMB*1024*1024, 10)
+	}
+	if err := writeCgroupValue(burn, "memory.high", memoryHigh); err != nil {
+		return "", err
+	}
+	return burn, nil
+}
+
+// burnMemoryHighMB is the memory.high of the burn cgroup: BURN_MEMORY_HIGH_MB,
+// or MAX_MEMORY_MB plus room for the child's runtime
+func burnMemoryHighMB(config Config) int64 {
+	if config.BurnMemoryHighMB > 0 {
+		return config.BurnMemoryHighMB
+	}
+	return config.MaxMemoryMB + burnChildMemoryHeadroomMB
+}
+
+func writeCgroupValue(dir, file, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s to %s: %v", value, filepath.Join(dir, file), err)
+	}
+	return nil
+}
+
+// startBurnChild sets up the burn cgroup and starts a burner process in it
+func startBurnChild(config Config, sources configSources) (*burnChild, error) {
+	parent, err := ownCgroupPath()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := setupBurnCgroup(parent, config.BurnCPUWeight, burnMemoryHighMB(config))
+	if err != nil {
+		return nil, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find own executable: %v", err)
+	}
+
+	// The child exits when its stdin closes, including when goburn dies
+	// It loads the same settings, so it fills memory the same way
+	cmd := exec.Command(exe, append([]string{burnerSubcommand}, sources.args()...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create burner stdin: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start burner process: %v", err)
+	}
+
+	// The child idles until its first command, so moving it right after start is safe
+	if err := writeCgroupValue(dir, "cgroup.procs", strconv.Itoa(cmd.Process.Pid)); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	child := &burnChild{dir: dir, cmd: cmd, stdin: stdin}
+	child.feedback(time.Now())
+	return child, nil
+}
+
+// send asks the child to burn value of a resource: "cpu <millicores>" or "mem <MB>"
+func (c *burnChild) send(resource string, value int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.stdin, "%s %d\n", resource, value); err != nil {
+		log.Printf("Failed to send %s %d to burner process: %v", resource, value, err)
+	}
+}
+
+// setMemoryHigh moves the memory.high of the burn cgroup, e.g. after a reload
+// raised MAX_MEMORY_MB
+func (c *burnChild) setMemoryHigh(memoryHighMB int64) error {
+	return writeCgroupValue(c.dir, "memory.high", strconv.FormatInt(memoryHighMB*1024*1024, 10))
+}
+
+// Stop closes the child's stdin and waits for it to release everything and exit
+func (c *burnChild) Stop() {
+	c.stdin.Close()
+	if c.cmd != nil {
+		c.cmd.Wait()
+	}
+}
+
+// feedback reads cpu.stat and memory.events of the burn cgroup
+func (c *burnChild) feedback(now time.Time) (burnFeedback, error) {
+	usageUsec, memoryBytes, err := readCgroupUsage(c.dir)
+	if err != nil {
+		return burnFeedback{}, err
+	}
+	events, err := readMemoryEvents(c.dir)
+	if err != nil {
+		return burnFeedback{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fb := burnFeedback{MemoryBytes: memoryBytes}
+	fb.CPUMillicores, fb.CPUKnown = c.cpu.update(usageUsec, now)
+	if high := events["high"]; high >= c.highEvents {
+		fb.MemoryHighEvents = high - c.highEvents
+		c.highEvents = high
+	}
+	return fb, nil
+}
+
+// readMemoryEvents parses the counters of a cgroup's memory.events
+func readMemoryEvents(dir string) (map[string]uint64, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory.events: %v", err)
+	}
+
+	events := make(map[string]uint64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse memory.events %s: %v", fields[0], err)
+		}
+		events[fields[0]] = value
+	}
+	return events, nil
+}
+
+// applyBurnFeedback holds scale-ups the kernel is not granting: CPU that the
+// burn cgroup cannot get because real workloads outweigh it, and memory that
+// already runs into memory.high
+func (rb *ResourceBurner) applyBurnFeedback(now time.Time) {
+	fb, err := rb.burnChild.feedback(now)
+	if err != nil {
+		log.Printf("Failed to read burn cgroup: %v", err)
+		return
+	}
+
+	requested := rb.cpuMillicores()
+	starved := fb.CPUKnown && requested > 0 && fb.CPUMillicores < float64(requested)/2
+	rb.cpuMutex.Lock()
+	rb.cpuStarved = starved
+	rb.cpuMutex.Unlock()
+
+	limited := fb.MemoryHighEvents > 0
+	rb.memoryMutex.Lock()
+	rb.memoryLimited = limited
+	rb.memoryMutex.Unlock()
+
+	log.Printf("🔥 Burn cgroup - CPU: %.0fm of %dm requested, Memory: %d MB, memory.high events: %d",
+		fb.CPUMillicores, requested, fb.MemoryBytes/1024/1024, fb.MemoryHighEvents)
+	if starved {
+		log.Printf("⏸️  CPU burn is starved by workloads with a higher cpu.weight - holding CPU scale-up")
+	}
+	if limited {
+		log.Printf("⏸️  Burn cgroup hit memory.high - holding memory scale-up")
+	}
+}
+
+// runBurnerChild is the entry point of the burner subcommand
+func runBurnerChild(args []string) {
+	flags := flag.NewFlagSet(burnerSubcommand, flag.ExitOnError)
+	sources := registerConfigFlags(flags)
+	flags.Parse(args)
+
+	config, err := loadConfig(*sources)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// The control loop caps what it asks for and the child never reloads, so
+	// its own cap would hold back a MAX_MEMORY_MB raised later
+	config.MaxMemoryMB = math.MaxInt64
+
+	rb := &ResourceBurner{
+		config:       config,
+		memory:       memoryBalloon{fill: newMemoryFiller(config.MemoryFill, config.MemoryFillRatio)},
+		stopChannels: make([]chan bool, 0),
+	}
+	go rb.memoryWorker()
+
+	if err := serveBurnCommands(rb, os.Stdin); err != nil {
+		log.Printf("Burner process stopped reading commands: %v", err)
+	}
+	rb.releaseCPULoad()
+	rb.releaseMemoryLoad()
+}
+
+// serveBurnCommands applies "cpu <millicores>" and "mem <MB>" lines until r is closed
+func serveBurnCommands(rb *ResourceBurner, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		resource, value, err := parseBurnCommand(scanner.Text())
+		if err != nil {
+			log.Printf("Ignoring burner command: %v", err)
+			continue
+		}
+
+		switch resource {
+		case "cpu":
+			rb.setCPUMillicores(value)
+		case "mem":
+			rb.setMemoryMB(value)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseBurnCommand(line string) (resource string, value int64, err error) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return "", 0, fmt.Errorf("malformed command %q", line)
+	}
+	if fields[0] != "cpu" && fields[0] != "mem" {
+		return "", 0, fmt.Errorf("unknown resource %q", fields[0])
+	}
+	value, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid value in %q: %v", line, err)
+	}
+	if value < 0 {
+		return "", 0, errors.New("negative value")
+	}
+	return fields[0], value, nil
+}
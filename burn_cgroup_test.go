@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// nopWriteCloser records what the control loop sends to the burner process
+type nopWriteCloser struct {
+	bytes.Buffer
+}
+
+func (w *nopWriteCloser) Close() error {
+	return nil
+}
+
+func readCgroupFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestSetupBurnCgroup(t *testing.T) {
+	parent := t.TempDir()
+	writeCgroupFile(t, parent, "cgroup.procs", "4242\n")
+
+	burn, err := setupBurnCgroup(parent, 1, 512)
+	if err != nil {
+		t.Fatalf("setupBurnCgroup() error = %v", err)
+	}
+	if burn != filepath.Join(parent, burnCgroupBurn) {
+		t.Errorf("burn cgroup = %s, want %s", burn, filepath.Join(parent, burnCgroupBurn))
+	}
+
+	if procs := readCgroupFile(t, filepath.Join(parent, burnCgroupControl), "cgroup.procs"); procs != "4242" {
+		t.Errorf("control cgroup.procs = %q, want the moved pid", procs)
+	}
+	if subtree := readCgroupFile(t, parent, "cgroup.subtree_control"); subtree != "+cpu +memory" {
+		t.Errorf("cgroup.subtree_control = %q", subtree)
+	}
+	if weight := readCgroupFile(t, burn, "cpu.weight"); weight != "1" {
+		t.Errorf("cpu.weight = %q, want 1", weight)
+	}
+	if high := readCgroupFile(t, burn, "memory.high"); high != "536870912" {
+		t.Errorf("memory.high = %q, want 512 MB in bytes", high)
+	}
+}
+
+func TestSetupBurnCgroup_FromControlLeaf(t *testing.T) {
+	parent := t.TempDir()
+	control := filepath.Join(parent, burnCgroupControl)
+	if err := os.MkdirAll(control, 0o755); err != nil {
+		t.Fatalf("failed to create control cgroup: %v", err)
+	}
+	writeCgroupFile(t, parent, "cgroup.procs", "")
+
+	// A restarted goburn starts out in the control leaf and must not nest another one
+	burn, err := setupBurnCgroup(control, 1, 0)
+	if err != nil {
+		t.Fatalf("setupBurnCgroup() error = %v", err)
+	}
+	if burn != filepath.Join(parent, burnCgroupBurn) {
+		t.Errorf("burn cgroup = %s, want a sibling of the control leaf", burn)
+	}
+	if high := readCgroupFile(t, burn, "memory.high"); high != "max" {
+		t.Errorf("memory.high = %q, want max", high)
+	}
+}
+
+func TestReadMemoryEvents(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFile(t, dir, "memory.events", "low 0\nhigh 17\nmax 2\noom 0\noom_kill 0\n")
+
+	events, err := readMemoryEvents(dir)
+	if err != nil {
+		t.Fatalf("readMemoryEvents() error = %v", err)
+	}
+	if events["high"] != 17 || events["max"] != 2 {
+		t.Errorf("events = %v, want high 17 max 2", events)
+	}
+
+	writeCgroupFile(t, dir, "memory.events", "high lots\n")
+	if _, err := readMemoryEvents(dir); err == nil {
+		t.Error("Expected error for malformed memory.events, got nil")
+	}
+}
+
+func TestBurnChild_Feedback(t *testing.T) {
+	dir := t.TempDir()
+	writeCgroupFile(t, dir, "cpu.stat", "usage_usec 1000000\n")
+	writeCgroupFile(t, dir, "memory.current", "209715200\n")
+	writeCgroupFile(t, dir, "memory.events", "high 3\n")
+
+	child := &burnChild{dir: dir}
+	start := time.Now()
+	if _, err := child.feedback(start); err != nil {
+		t.Fatalf("feedback() error = %v", err)
+	}
+
+	writeCgroupFile(t, dir, "cpu.stat", "usage_usec 1250000\n")
+	writeCgroupFile(t, dir, "memory.events", "high 5\n")
+	fb, err := child.feedback(start.Add(time.Second))
+	if err != nil {
+		t.Fatalf("feedback() error = %v", err)
+	}
+	if !fb.CPUKnown || fb.CPUMillicores != 250 {
+		t.Errorf("CPU = %vm (known %v), want 250m", fb.CPUMillicores, fb.CPUKnown)
+	}
+	if fb.MemoryBytes != 200*1024*1024 {
+		t.Errorf("MemoryBytes = %d, want 200 MB", fb.MemoryBytes)
+	}
+	if fb.MemoryHighEvents != 2 {
+		t.Errorf("MemoryHighEvents = %d, want the 2 new events", fb.MemoryHighEvents)
+	}
+}
+
+func TestResourceBurner_RoutesToBurnChild(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	stdin := &nopWriteCloser{}
+	rb.burnChild = &burnChild{stdin: stdin}
+
+	rb.setCPUMillicores(500)
+	rb.setMemoryMB(4)
+	rb.releaseCPULoad()
+	rb.releaseMemoryLoad()
+
+	if rb.cpuWorkers != 0 || len(rb.stopChannels) != 0 {
+		t.Errorf("Expected no in-process CPU workers, got %d", len(rb.stopChannels))
+	}
+	if rb.memory.SizeMB() != 0 {
+		t.Errorf("Expected no in-process memory, got %d MB", rb.memory.SizeMB())
+	}
+
+	expected := "cpu 500\nmem 4\ncpu 0\nmem 0\n"
+	if stdin.String() != expected {
+		t.Errorf("sent %q, want %q", stdin.String(), expected)
+	}
+}
+
+func TestResourceBurner_BurnHolds(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.burnChild = &burnChild{stdin: &nopWriteCloser{}}
+
+	rb.setCPUMillicores(500)
+	rb.setMemoryMB(4)
+
+	rb.cpuStarved = true
+	rb.memoryLimited = true
+
+	if applied := rb.setCPUMillicores(900); applied != 500 {
+		t.Errorf("setCPUMillicores() while starved = %d, want held at 500", applied)
+	}
+	if size := rb.setMemoryMB(8); size != 4 {
+		t.Errorf("setMemoryMB() while limited = %d, want held at 4", size)
+	}
+
+	// Scaling down is always allowed
+	if applied := rb.setCPUMillicores(200); applied != 200 {
+		t.Errorf("setCPUMillicores(200) = %d, want 200", applied)
+	}
+	rb.adjustMemoryLoad(90.0, 10.0)
+	if rb.memorySizeMB() != 4 {
+		t.Errorf("adjustMemoryLoad() while limited grew memory to %d MB", rb.memorySizeMB())
+	}
+}
+
+func TestServeBurnCommands(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.MaxMemoryMB = 8
+	defer rb.releaseCPULoad()
+
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- serveBurnCommands(rb, reader)
+	}()
+
+	commands := []string{"cpu 300", "mem 2", "bogus 1", "mem -1", "cpu"}
+	if _, err := writer.Write([]byte(strings.Join(commands, "\n") + "\n")); err != nil {
+		t.Fatalf("failed to write commands: %v", err)
+	}
+	writer.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("serveBurnCommands() error = %v", err)
+	}
+	if rb.cpuMillicores() != 300 {
+		t.Errorf("cpuMillicores() = %d, want 300", rb.cpuMillicores())
+	}
+	if rb.memorySizeMB() != 2 {
+		t.Errorf("memorySizeMB() = %d, want 2", rb.memorySizeMB())
+	}
+}
+
+func TestParseBurnCommand(t *testing.T) {
+	resource, value, err := parseBurnCommand("mem 128")
+	if err != nil || resource != "mem" || value != 128 {
+		t.Errorf("parseBurnCommand(mem 128) = %s, %d, %v", resource, value, err)
+	}
+
+	for _, line := range []string{"", "cpu", "cpu x", "disk 10", "cpu -5", "cpu 1 2"} {
+		if _, _, err := parseBurnCommand(line); err == nil {
+			t.Errorf("parseBurnCommand(%q) expected error, got nil", line)
+		}
+	}
+}
+
+func TestResourceBurner_ApplyConfigMovesBurnMemoryHigh(t *testing.T) {
+	dir := t.TempDir()
+	rb := createTestResourceBurner(t)
+	rb.burnChild = &burnChild{dir: dir, stdin: &nopWriteCloser{}}
+
+	next := rb.config
+	next.MaxMemoryMB = 4096
+	rb.applyConfig(configReload{config: next, source: "test.yaml"}, nil)
+
+	data, err := os.ReadFile(filepath.Join(dir, "memory.high"))
+	if err != nil {
+		t.Fatalf("failed to read memory.high: %v", err)
+	}
+	if want := strconv.FormatInt((4096+burnChildMemoryHeadroomMB)*1024*1024, 10); string(data) != want {
+		t.Errorf("memory.high = %s, want %s", data, want)
+	}
+	if size := rb.setMemoryMB(2048); size != 2048 {
+		t.Errorf("setMemoryMB(2048) = %d, want the raised cap to apply", size)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"math"
+	"runtime"
+	"time"
+)
+
+// Supported values for CPU_STRATEGY
+const (
+	CPUStrategyContinuous = "continuous"
+	CPUStrategyBurst      = "burst"
+)
+
+// burstPlanner satisfies the CPU percentile rule with short bursts instead of
+// a continuous burn. A p95 rule only needs just over 5% of the samples at or
+// above the minimum, so the planner burns through one sample out of every
+// few and leaves the CPU idle otherwise.
+type burstPlanner struct {
+	sinceHigh int // samples since the last one at or above the minimum
+}
+
+// burstPlan is the planner's decision for the next sample
+type burstPlan struct {
+	Samples int  // samples in the window
+	High    int  // samples at or above the minimum
+	Needed  int  // high samples the percentile needs if the next sample is low
+	Every   int  // pacing: one high sample every this many samples
+	Burst   bool // burn through the next sample
+	CatchUp bool // bursting because the window is short of high samples
+}
+
+// burstEvery returns how often a sample must be high: the share the percentile
+// leaves above it, plus margin, e.g. 1 in 16 for p95 with a 25% margin
+func burstEvery(percentile, margin float64) int {
+	share := (100 - percentile) / 100 * (1 + margin)
+	if share <= 0 {
+		return 1
+	}
+	if every := int(math.Floor(1 / share)); every > 1 {
+		return every
+	}
+	return 1
+}
+
+// highSamplesNeeded returns how many of n samples must be at or above the
+// minimum for the percentile, by nearest rank, to be at or above it too
+func highSamplesNeeded(n int, percentile float64) int {
+	if n <= 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(n)))
+	return n - rank + 1
+}
+
+// Plan decides whether to burst through the next sample. Bursts are paced
+// evenly so every stretch of the rolling window holds its share, and the
+// planner bursts every sample while the window is short of high samples, e.g.
+// after a cold start. Samples that workloads push above the minimum count as
+// bursts, so busy nodes are never burned on.
+func (p *burstPlanner) Plan(window *percentileWindow, minimum, margin float64, lastHigh bool) burstPlan {
+	if lastHigh {
+		p.sinceHigh = 0
+	} else {
+		p.sinceHigh++
+	}
+
+	plan := burstPlan{
+		Samples: window.Count(),
+		High:    window.CountAtLeast(minimum),
+		Every:   burstEvery(window.Percentile(), margin),
+	}
+	plan.Needed = highSamplesNeeded(plan.Samples+1, window.Percentile())
+	plan.CatchUp = plan.High < plan.Needed
+	plan.Burst = plan.CatchUp || p.sinceHigh+1 >= plan.Every
+	return plan
+}
+
+// runBurstPlan burns CPU for the next sample only when the planner asks for
+// it. A burst lifts node CPU to the minimum plus BurstHeadroom on top of what
+// the workloads already use.
+func (rb *ResourceBurner) runBurstPlan(usage NodeUsage, workload WorkloadUsage, workloadKnown bool, now time.Time) {
+	level := rb.config.MinCPUUtilization + rb.config.BurstHeadroom
+
+	rb.cpuSampleMutex.Lock()
+	plan := rb.burst.Plan(rb.cpuSamples, rb.config.MinCPUUtilization, rb.config.BurstMargin,
+		usage.CPUPercent >= rb.config.MinCPUUtilization)
+	planned := rb.cpuSamples.PlannedPercentile(now, 1/float64(plan.Every), level)
+	rb.cpuSampleMutex.Unlock()
+
+	capacity := int64(runtime.NumCPU()) * cpuFullCore
+	if usage.CPUCapacity > 0 {
+		capacity = usage.CPUCapacity
+	}
+
+	millicores := int64(0)
+	if plan.Burst {
+		foreign := usage.CPUPercent - float64(rb.cpuMillicores())/float64(capacity)*100
+		if workloadKnown {
+			foreign = workload.ForeignCPUPercent
+		}
+		millicores = int64(math.Round(math.Max(0, level-foreign) / 100 * float64(capacity)))
+	}
+	applied := rb.setCPUMillicores(millicores)
+
+	state := "idle"
+	if plan.CatchUp {
+		state = "catching up"
+	} else if plan.Burst {
+		state = "burst"
+	}
+	log.Printf("🎯 Burst plan - %s (%dm), high samples: %d/%d (need %d), one in %d, planned p%g: %.1f%%",
+		state, applied, plan.High, plan.Samples, plan.Needed, plan.Every, rb.cpuSamples.Percentile(), planned)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBurstEvery(t *testing.T) {
+	tests := []struct {
+		percentile, margin float64
+		want               int
+	}{
+		{95, 0.25, 16},
+		{95, 0, 20},
+		{99, 0.25, 80},
+		{90, 0.25, 8},
+		{100, 0.25, 1},
+	}
+	for _, tt := range tests {
+		if got := burstEvery(tt.percentile, tt.margin); got != tt.want {
+			t.Errorf("burstEvery(%g, %g) = %d, want %d", tt.percentile, tt.margin, got, tt.want)
+		}
+	}
+}
+
+func TestHighSamplesNeeded(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{0, 0},
+		{1, 1},
+		{20, 2},
+		{100, 6},
+		{2016, 101},
+	}
+	for _, tt := range tests {
+		if got := highSamplesNeeded(tt.n, 95); got != tt.want {
+			t.Errorf("highSamplesNeeded(%d, 95) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestBurstPlanner_MeetsPercentile(t *testing.T) {
+	window := newPercentileWindow(24*time.Hour, 95, 30*time.Second)
+	var planner burstPlanner
+	start := time.Unix(1700000040, 0)
+
+	// An idle node at 5% where a burst lifts the sample to 30%
+	const ticks = 2880
+	bursts := 0
+	burst := false
+	for i := 0; i < ticks; i++ {
+		sample := 5.0
+		if burst {
+			sample = 30
+			bursts++
+		}
+		window.Add(sample, start.Add(time.Duration(i)*30*time.Second))
+		burst = planner.Plan(window, 20, 0.25, sample >= 20).Burst
+	}
+
+	if p95 := window.Value(); p95 < 20 {
+		t.Errorf("p95 = %.1f%%, want at least the 20%% minimum", p95)
+	}
+	// Just over 5% of the samples, not a continuous burn
+	if share := float64(bursts) / ticks; share > 0.08 {
+		t.Errorf("burst share = %.1f%%, want close to 6%%", share*100)
+	}
+}
+
+func TestBurstPlanner_BusyNodeNeedsNoBursts(t *testing.T) {
+	window := newPercentileWindow(time.Hour, 95, 30*time.Second)
+	var planner burstPlanner
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		window.Add(50, now)
+		if plan := planner.Plan(window, 20, 0.25, true); plan.Burst {
+			t.Fatalf("sample %d: planner bursts on a node that is already above the minimum", i)
+		}
+	}
+}
+
+func TestBurstPlanner_CatchUp(t *testing.T) {
+	window := newPercentileWindow(time.Hour, 95, 30*time.Second)
+	var planner burstPlanner
+	now := time.Now()
+
+	// 40 idle samples restored from a checkpoint need 3 high ones
+	for i := 0; i < 40; i++ {
+		window.Add(5, now)
+	}
+	plan := planner.Plan(window, 20, 0.25, false)
+	if !plan.Burst || !plan.CatchUp || plan.Needed != 3 || plan.High != 0 {
+		t.Errorf("Plan() = %+v, want a catch-up burst needing 3", plan)
+	}
+}
+
+func TestPercentileWindow_PlannedPercentile(t *testing.T) {
+	window := newPercentileWindow(time.Hour, 95, 30*time.Second)
+	now := time.Now()
+	for i := 0; i < 60; i++ {
+		window.Add(5, now)
+	}
+
+	if got := window.PlannedPercentile(now, 1.0/16, 30); got != 30 {
+		t.Errorf("PlannedPercentile(1/16) = %.1f, want the 30%% burst level", got)
+	}
+	if got := window.PlannedPercentile(now, 1.0/25, 30); got != 5 {
+		t.Errorf("PlannedPercentile(1/25) = %.1f, want the 5%% idle level", got)
+	}
+	if got := newPercentileWindow(time.Hour, 95, 0).PlannedPercentile(now, 0.1, 30); got != 30 {
+		t.Errorf("PlannedPercentile() on an empty window = %.1f, want the burst level", got)
+	}
+}
+
+func TestResourceBurner_RunBurstPlan(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.CPUStrategy = CPUStrategyBurst
+	rb.config.MinCPUUtilization = 20
+	rb.config.BurstHeadroom = 10
+	rb.config.BurstMargin = 0.25
+	defer rb.releaseCPULoad()
+
+	usage := NodeUsage{CPUPercent: 5, CPUCapacity: 1000}
+	workload := WorkloadUsage{ForeignCPUPercent: 5}
+	now := time.Now()
+
+	// Cold start: the first sample must already be high
+	rb.addCPUSample(usage.CPUPercent)
+	rb.runBurstPlan(usage, workload, true, now)
+	if rb.cpuMillicores() != 250 {
+		t.Errorf("cpuMillicores() = %d, want 250m to lift 5%% to 30%%", rb.cpuMillicores())
+	}
+
+	// Once the window holds enough high samples the burn stops
+	for i := 0; i < 5; i++ {
+		rb.addCPUSample(30)
+	}
+	rb.runBurstPlan(NodeUsage{CPUPercent: 30, CPUCapacity: 1000}, WorkloadUsage{ForeignCPUPercent: 5}, true, now)
+	if rb.cpuMillicores() != 0 {
+		t.Errorf("cpuMillicores() = %d, want no burn between bursts", rb.cpuMillicores())
+	}
+}
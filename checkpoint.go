@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Supported values for CHECKPOINT_STORE
+const (
+	CheckpointStoreFile      = "file"
+	CheckpointStoreConfigMap = "configmap"
+)
+
+// checkpointMagic and checkpointVersion start the header line of every
+// checkpoint: "goburn-history <version> <crc32 of the body>". Version 1 only
+// held the CPU window and version 2 stored the bins as JSON numbers; both are
+// still read. Version 3 packs the bins and gzips the body.
+const (
+	checkpointMagic   = "goburn-history"
+	checkpointVersion = 3
+)
+
+// checkpointConfigMapKey is the ConfigMap data key holding the checkpoint
+const checkpointConfigMapKey = "history"
+
+// configMapMaxBytes is the most data the API server accepts in one ConfigMap
+const configMapMaxBytes = 1 << 20
+
+// checkpointMaxBodyBytes caps how far a checkpoint body is decompressed
+const checkpointMaxBodyBytes = 16 << 20
+
+// errNoCheckpoint is returned by a store that has nothing saved yet
+var errNoCheckpoint = errors.New("no checkpoint saved")
+
+// checkpointStore keeps the latest checkpoint of this node
+type checkpointStore interface {
+	Name() string
+	Load(ctx context.Context) ([]byte, error)
+	Save(ctx context.Context, data []byte) error
+}
+
+func newCheckpointStore(config Config, k8sClient kubernetes.Interface) (checkpointStore, error) {
+	switch config.CheckpointStore {
+	case CheckpointStoreFile:
+		return &fileCheckpointStore{path: config.CheckpointPath}, nil
+	case CheckpointStoreConfigMap:
+		return &configMapCheckpointStore{
+			k8sClient: k8sClient,
+			namespace: config.PodNamespace,
+			name:      "goburn-history-" + config.NodeName,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown checkpoint store %q", config.CheckpointStore)
+	}
+}
+
+// historyCheckpoint is the body of a checkpoint. Buckets is the CPU window;
+// the network bins are relative to NetworkScale.
+type historyCheckpoint struct {
+	Version      int            `json:"version"`
+	NodeName     string         `json:"nodeName"`
+	SavedAt      time.Time      `json:"savedAt"`
+	Buckets      historyBuckets `json:"buckets,omitempty"`
+	Memory       historyBuckets `json:"memory,omitempty"`
+	Network      historyBuckets `json:"network,omitempty"`
+	NetworkScale float64        `json:"networkScale,omitempty"`
+}
+
+// checkpointWindows are the samples a checkpoint holds: the CPU window and
+// the memory and network compliance windows
+type checkpointWindows struct {
+	cpu          []percentileBucket
+	memory       []percentileBucket
+	network      []percentileBucket
+	networkScale float64
+}
+
+// historyBucket is one minute of samples as histogram bins
+type historyBucket struct {
+	Minute int64    `json:"m"`
+	Bins   []uint16 `json:"b"`
+}
+
+// historyBuckets is a window as it is stored: packed into a string of, per
+// bucket, the minute as a uvarint delta to the previous bucket, the number of
+// samples as a uvarint and the bins as little-endian uint16s. Versions 1 and
+// 2 stored a JSON array of buckets, which is still read.
+type historyBuckets []historyBucket
+
+func (h historyBuckets) MarshalJSON() ([]byte, error) {
+	return json.Marshal(packHistoryBuckets(h))
+}
+
+func (h *historyBuckets) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return json.Unmarshal(data, (*[]historyBucket)(h))
+	}
+	var packed []byte
+	if err := json.Unmarshal(data, &packed); err != nil {
+		return err
+	}
+	buckets, err := unpackHistoryBuckets(packed)
+	if err != nil {
+		return err
+	}
+	*h = buckets
+	return nil
+}
+
+func packHistoryBuckets(buckets []historyBucket) []byte {
+	var packed []byte
+	var prev int64
+	for _, b := range buckets {
+		packed = binary.AppendUvarint(packed, uint64(b.Minute-prev))
+		packed = binary.AppendUvarint(packed, uint64(len(b.Bins)))
+		for _, bin := range b.Bins {
+			packed = binary.LittleEndian.AppendUint16(packed, bin)
+		}
+		prev = b.Minute
+	}
+	return packed
+}
+
+func unpackHistoryBuckets(packed []byte) ([]historyBucket, error) {
+	var buckets []historyBucket
+	var minute int64
+	for len(packed) > 0 {
+		delta, n := binary.Uvarint(packed)
+		if n <= 0 {
+			return nil, errors.New("malformed packed bucket minute")
+		}
+		packed = packed[n:]
+		count, n := binary.Uvarint(packed)
+		if n <= 0 || count > uint64(len(packed[n:])/2) {
+			return nil, errors.New("malformed packed bucket samples")
+		}
+		packed = packed[n:]
+
+		minute += int64(delta)
+		bins := make([]uint16, count)
+		for i := range bins {
+			bins[i] = binary.LittleEndian.Uint16(packed[2*i:])
+		}
+		packed = packed[2*count:]
+		buckets = append(buckets, historyBucket{Minute: minute, Bins: bins})
+	}
+	return buckets, nil
+}
+
+// checkpointMaxSize returns the largest checkpoint config can produce: every
+// window full, one sample per monitor interval, with bins that do not
+// compress at all
+func checkpointMaxSize(config Config) int {
+	windows := 2
+	if config.EnableMemoryUtilization {
+		windows++
+	}
+	window := config.PercentileWindow
+	if window <= 0 {
+		window = defaultPercentileWindow
+	}
+
+	// Buckets are dropped by the minute, so a window holds up to a minute more
+	minutes := int64(window / time.Minute)
+	buckets := minutes + 2
+	samples := int64((window+time.Minute)/config.MonitorInterval) + 1
+	packed := binary.MaxVarintLen64 +
+		buckets*int64(uvarintLen(uint64(minutes+1))+uvarintLen(uint64(samples))) +
+		samples*2
+	encoded := (packed + 2) / 3 * 4
+
+	// The metadata, and deflate's worst case of storing the body uncompressed
+	const overhead = 1024
+	body := int64(windows)*encoded + overhead
+	return int(body + 5*(body/65535+1))
+}
+
+func uvarintLen(v uint64) int {
+	return len(binary.AppendUvarint(nil, v))
+}
+
+// historyGap is a stretch of time without any CPU sample
+type historyGap struct {
+	From time.Time
+	To   time.Time
+}
+
+func encodeCheckpoint(nodeName string, windows checkpointWindows, now time.Time) ([]byte, error) {
+	cp := historyCheckpoint{
+		Version:      checkpointVersion,
+		NodeName:     nodeName,
+		SavedAt:      now.UTC(),
+		Buckets:      toHistoryBuckets(windows.cpu),
+		Memory:       toHistoryBuckets(windows.memory),
+		Network:      toHistoryBuckets(windows.network),
+		NetworkScale: windows.networkScale,
+	}
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if err := json.NewEncoder(zw).Encode(cp); err != nil {
+		return nil, fmt.Errorf("failed to encode checkpoint: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress checkpoint: %v", err)
+	}
+	header := fmt.Sprintf("%s %d %08x\n", checkpointMagic, checkpointVersion, crc32.ChecksumIEEE(body.Bytes()))
+	return append([]byte(header), body.Bytes()...), nil
+}
+
+// decodeCheckpoint checks the header, version and checksum before trusting
+// anything in the body, so a torn or hand-edited checkpoint is rejected
+func decodeCheckpoint(data []byte) (historyCheckpoint, error) {
+	header, body, found := bytes.Cut(data, []byte("\n"))
+	if !found {
+		return historyCheckpoint{}, errors.New("checkpoint has no header")
+	}
+
+	fields := strings.Fields(string(header))
+	if len(fields) != 3 || fields[0] != checkpointMagic {
+		return historyCheckpoint{}, fmt.Errorf("malformed checkpoint header %q", header)
+	}
+	version, err := strconv.Atoi(fields[1])
+	if err != nil || version < 1 || version > checkpointVersion {
+		return historyCheckpoint{}, fmt.Errorf("unsupported checkpoint version %q", fields[1])
+	}
+	checksum, err := strconv.ParseUint(fields[2], 16, 32)
+	if err != nil {
+		return historyCheckpoint{}, fmt.Errorf("malformed checkpoint checksum %q", fields[2])
+	}
+	if uint32(checksum) != crc32.ChecksumIEEE(body) {
+		return historyCheckpoint{}, errors.New("checkpoint checksum mismatch")
+	}
+	if version >= 3 {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return historyCheckpoint{}, fmt.Errorf("failed to decompress checkpoint: %v", err)
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, checkpointMaxBodyBytes))
+		if err != nil {
+			return historyCheckpoint{}, fmt.Errorf("failed to decompress checkpoint: %v", err)
+		}
+	}
+
+	var cp historyCheckpoint
+	if err := json.Unmarshal(body, &cp); err != nil {
+		return historyCheckpoint{}, fmt.Errorf("failed to decode checkpoint: %v", err)
+	}
+	if cp.Version != version {
+		return historyCheckpoint{}, fmt.Errorf("checkpoint body version %d does not match header version %d", cp.Version, version)
+	}
+	for _, buckets := range []historyBuckets{cp.Buckets, cp.Memory, cp.Network} {
+		for i, b := range buckets {
+			if i > 0 && b.Minute <= buckets[i-1].Minute {
+				return historyCheckpoint{}, errors.New("checkpoint buckets are out of order")
+			}
+			for _, bin := range b.Bins {
+				if bin >= percentileBins {
+					return historyCheckpoint{}, fmt.Errorf("checkpoint sample %d is out of range", bin)
+				}
+			}
+		}
+	}
+	return cp, nil
+}
+
+func toHistoryBuckets(buckets []percentileBucket) historyBuckets {
+	var history historyBuckets
+	for _, b := range buckets {
+		history = append(history, historyBucket{Minute: b.minute, Bins: b.bins})
+	}
+	return history
+}
+
+func fromHistoryBuckets(history []historyBucket) []percentileBucket {
+	buckets := make([]percentileBucket, len(history))
+	for i, b := range history {
+		buckets[i] = percentileBucket{minute: b.Minute, bins: b.Bins}
+	}
+	return buckets
+}
+
+// findHistoryGaps returns every stretch longer than maxGap without samples,
+// between buckets and from the last bucket up to now
+func findHistoryGaps(buckets []historyBucket, now time.Time, maxGap time.Duration) []historyGap {
+	var gaps []historyGap
+	for i := 1; i < len(buckets); i++ {
+		from := time.Unix(buckets[i-1].Minute*60+60, 0)
+		to := time.Unix(buckets[i].Minute*60, 0)
+		if to.Sub(from) > maxGap {
+			gaps = append(gaps, historyGap{From: from, To: to})
+		}
+	}
+	if len(buckets) > 0 {
+		from := time.Unix(buckets[len(buckets)-1].Minute*60+60, 0)
+		if now.Sub(from) > maxGap {
+			gaps = append(gaps, historyGap{From: from, To: now})
+		}
+	}
+	return gaps
+}
+
+// historyMaxGap is how long the history may go without samples before it
+// counts as a gap: a few missed monitor ticks, and never less than two minutes
+func historyMaxGap(monitorInterval time.Duration) time.Duration {
+	return time.Duration(max(int64(3*monitorInterval), int64(2*time.Minute)))
+}
+
+// restoreHistory loads the last checkpoint into the CPU percentile window and
+// the memory and network compliance windows. Anything wrong with it is logged
+// and goburn starts with an empty history.
+func (rb *ResourceBurner) restoreHistory(ctx context.Context, store checkpointStore, now time.Time) {
+	data, err := store.Load(ctx)
+	if errors.Is(err, errNoCheckpoint) {
+		log.Printf("💾 No history checkpoint in %s yet, starting with an empty CPU window", store.Name())
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️  Cannot load history checkpoint from %s, starting with an empty CPU window: %v", store.Name(), err)
+		return
+	}
+
+	cp, err := decodeCheckpoint(data)
+	if err != nil {
+		log.Printf("⚠️  Ignoring corrupt history checkpoint in %s: %v", store.Name(), err)
+		return
+	}
+	if cp.NodeName != rb.config.NodeName {
+		log.Printf("⚠️  Ignoring history checkpoint of node %s in %s", cp.NodeName, store.Name())
+		return
+	}
+
+	rb.cpuSampleMutex.Lock()
+	rb.cpuSamples.Restore(fromHistoryBuckets(cp.Buckets), now)
+	restored := rb.cpuSamples.Count()
+	if rb.compliance == nil {
+		rb.compliance = newComplianceTracker(rb.config, rb.cpuSamples)
+	}
+	var memoryRestored, networkRestored int
+	if memory := rb.compliance.window(ResourceMemory); memory != nil {
+		memory.Restore(fromHistoryBuckets(cp.Memory), now)
+		memoryRestored = memory.Count()
+	}
+	network := rb.compliance.window(ResourceNetwork)
+	networkScaled := cp.NetworkScale == 0 || cp.NetworkScale == network.Scale()
+	if networkScaled {
+		network.Restore(fromHistoryBuckets(cp.Network), now)
+		networkRestored = network.Count()
+	}
+	rb.cpuSampleMutex.Unlock()
+
+	log.Printf("💾 Restored %d CPU, %d memory and %d network samples saved %s ago from %s",
+		restored, memoryRestored, networkRestored, now.Sub(cp.SavedAt).Round(time.Second), store.Name())
+	if !networkScaled {
+		log.Printf("⚠️  Dropped the network history, saved at a %.0f Mbps scale instead of %.0f Mbps", cp.NetworkScale, network.Scale())
+	}
+	for _, gap := range findHistoryGaps(cp.Buckets, now, historyMaxGap(rb.config.MonitorInterval)) {
+		log.Printf("⚠️  CPU history gap of %s from %s to %s",
+			gap.To.Sub(gap.From).Round(time.Second), gap.From.UTC().Format(time.RFC3339), gap.To.UTC().Format(time.RFC3339))
+	}
+}
+
+// saveHistory writes the CPU percentile window and the compliance windows to the store
+func (rb *ResourceBurner) saveHistory(ctx context.Context, store checkpointStore, now time.Time) error {
+	rb.cpuSampleMutex.RLock()
+	windows := checkpointWindows{cpu: rb.cpuSamples.Buckets()}
+	if rb.compliance != nil {
+		if memory := rb.compliance.window(ResourceMemory); memory != nil {
+			windows.memory = memory.Buckets()
+		}
+		if network := rb.compliance.window(ResourceNetwork); network != nil {
+			windows.network = network.Buckets()
+			windows.networkScale = network.Scale()
+		}
+	}
+	rb.cpuSampleMutex.RUnlock()
+
+	data, err := encodeCheckpoint(rb.currentConfig().NodeName, windows, now)
+	if err != nil {
+		return err
+	}
+	return store.Save(ctx, data)
+}
+
+// checkpointWorker saves the history every CheckpointInterval; Run saves it
+// once more after the monitor loop stops
+func (rb *ResourceBurner) checkpointWorker(ctx context.Context, store checkpointStore) {
+	interval := rb.currentConfig().CheckpointInterval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := rb.saveHistory(ctx, store, now); err != nil {
+				log.Printf("Failed to save history checkpoint to %s: %v", store.Name(), err)
+			}
+		}
+	}
+}
+
+// fileCheckpointStore keeps the checkpoint in a file, e.g. on a hostPath
+// volume so it survives pod restarts on the same node
+type fileCheckpointStore struct {
+	path string
+}
+
+func (s *fileCheckpointStore) Name() string {
+	return s.path
+}
+
+func (s *fileCheckpointStore) Load(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errNoCheckpoint
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", s.path, err)
+	}
+	return data, nil
+}
+
+// Save writes a temporary file and renames it over the checkpoint, so a crash
+// mid-write leaves the previous checkpoint intact
+func (s *fileCheckpointStore) Save(ctx context.Context, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync checkpoint: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %v", err)
+	}
+	return nil
+}
+
+// configMapCheckpointStore keeps the checkpoint in a ConfigMap per node
+type configMapCheckpointStore struct {
+	k8sClient kubernetes.Interface
+	namespace string
+	name      string
+}
+
+func (s *configMapCheckpointStore) Name() string {
+	return "configmap " + s.namespace + "/" + s.name
+}
+
+// Load reads the binary checkpoint, or the text one written before version 3
+func (s *configMapCheckpointStore) Load(ctx context.Context) ([]byte, error) {
+	cm, err := s.k8sClient.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, errNoCheckpoint
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get configmap: %v", err)
+	}
+	if data, ok := cm.BinaryData[checkpointConfigMapKey]; ok {
+		return data, nil
+	}
+	data, ok := cm.Data[checkpointConfigMapKey]
+	if !ok {
+		return nil, errNoCheckpoint
+	}
+	return []byte(data), nil
+}
+
+// Save replaces the whole ConfigMap data in one update, which the API server
+// applies atomically. A checkpoint over the ConfigMap limit is an error
+// rather than a rejected update.
+func (s *configMapCheckpointStore) Save(ctx context.Context, data []byte) error {
+	if len(data) > configMapMaxBytes {
+		return fmt.Errorf("checkpoint of %d KB exceeds the %d KB ConfigMap limit", len(data)/1024, configMapMaxBytes/1024)
+	}
+	configMaps := s.k8sClient.CoreV1().ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.name,
+				Namespace: s.namespace,
+				Labels:    map[string]string{"app": "goburn"},
+			},
+			BinaryData: map[string][]byte{checkpointConfigMapKey: data},
+		}
+		if _, err := configMaps.Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create configmap: %v", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get configmap: %v", err)
+	}
+
+	cm.Data = nil
+	cm.BinaryData = map[string][]byte{checkpointConfigMapKey: data}
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update configmap: %v", err)
+	}
+	return nil
+}
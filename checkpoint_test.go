@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testHistoryBuckets(start time.Time) []percentileBucket {
+	minute := start.Unix() / 60
+	return []percentileBucket{
+		{minute: minute, bins: []uint16{100, 200}},
+		{minute: minute + 1, bins: []uint16{300}},
+		{minute: minute + 10, bins: []uint16{1000, 0}},
+	}
+}
+
+func TestCheckpoint_RoundTrip(t *testing.T) {
+	now := time.Now()
+	data, err := encodeCheckpoint("node-a", checkpointWindows{cpu: testHistoryBuckets(now)}, now)
+	if err != nil {
+		t.Fatalf("encodeCheckpoint() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), "goburn-history 3 ") {
+		t.Errorf("checkpoint starts with %q, want the version header", strings.SplitN(string(data), "\n", 2)[0])
+	}
+
+	cp, err := decodeCheckpoint(data)
+	if err != nil {
+		t.Fatalf("decodeCheckpoint() error = %v", err)
+	}
+	if cp.NodeName != "node-a" || len(cp.Buckets) != 3 || cp.Buckets[2].Bins[0] != 1000 {
+		t.Errorf("decodeCheckpoint() = %+v", cp)
+	}
+	if !cp.SavedAt.Equal(now) {
+		t.Errorf("SavedAt = %v, want %v", cp.SavedAt, now)
+	}
+}
+
+func TestDecodeCheckpoint_Corrupt(t *testing.T) {
+	now := time.Now()
+	data, err := encodeCheckpoint("node-a", checkpointWindows{cpu: testHistoryBuckets(now)}, now)
+	if err != nil {
+		t.Fatalf("encodeCheckpoint() error = %v", err)
+	}
+	header, body, _ := strings.Cut(string(data), "\n")
+
+	flipped := []byte(data)
+	flipped[len(flipped)-3] ^= 0x01
+
+	tests := map[string]string{
+		"empty":        "",
+		"no header":    body,
+		"bad magic":    strings.Replace(string(data), checkpointMagic, "other-history", 1),
+		"future":       strings.Replace(string(data), checkpointMagic+" 3 ", checkpointMagic+" 4 ", 1),
+		"bad checksum": header[:len(header)-8] + "zzzzzzzz\n" + body,
+		"flipped byte": string(flipped),
+		"truncated":    string(data[:len(data)-10]),
+		"not gzip":     fmt.Sprintf("%s 3 %08x\n{}", checkpointMagic, crc32.ChecksumIEEE([]byte("{}"))),
+		"invalid json": checkpointWithBody(t, "{"),
+		"bad packing":  checkpointWithBody(t, `{"version":2,"nodeName":"a","buckets":"BQM="}`),
+		"out of order": checkpointWithBody(t, `{"version":2,"nodeName":"a","buckets":[{"m":5,"b":[1]},{"m":4,"b":[1]}]}`),
+		"out of range": checkpointWithBody(t, `{"version":2,"nodeName":"a","buckets":[{"m":5,"b":[1001]}]}`),
+		"bad memory":   checkpointWithBody(t, `{"version":2,"nodeName":"a","memory":[{"m":5,"b":[1001]}]}`),
+		"bad network":  checkpointWithBody(t, `{"version":2,"nodeName":"a","network":[{"m":5,"b":[1]},{"m":5,"b":[1]}]}`),
+		"body version": checkpointWithBody(t, `{"version":7,"nodeName":"a"}`),
+	}
+	for name, data := range tests {
+		if _, err := decodeCheckpoint([]byte(data)); err == nil {
+			t.Errorf("%s: decodeCheckpoint() expected error, got nil", name)
+		}
+	}
+}
+
+func TestDecodeCheckpoint_Version1(t *testing.T) {
+	body := `{"version":1,"nodeName":"a","buckets":[{"m":5,"b":[100]}]}`
+	data := fmt.Sprintf("%s 1 %08x\n%s", checkpointMagic, crc32.ChecksumIEEE([]byte(body)), body)
+
+	cp, err := decodeCheckpoint([]byte(data))
+	if err != nil {
+		t.Fatalf("decodeCheckpoint() error = %v", err)
+	}
+	if len(cp.Buckets) != 1 || cp.Memory != nil || cp.Network != nil {
+		t.Errorf("decodeCheckpoint() = %+v, want the CPU window only", cp)
+	}
+}
+
+// checkpointWithBody wraps body in a valid version 2 header, which is not
+// compressed, so only the body is wrong
+func checkpointWithBody(t *testing.T, body string) string {
+	t.Helper()
+	return fmt.Sprintf("%s 2 %08x\n%s", checkpointMagic, crc32.ChecksumIEEE([]byte(body)), body)
+}
+
+func TestPackHistoryBuckets(t *testing.T) {
+	buckets := toHistoryBuckets(testHistoryBuckets(time.Now()))
+	unpacked, err := unpackHistoryBuckets(packHistoryBuckets(buckets))
+	if err != nil {
+		t.Fatalf("unpackHistoryBuckets() error = %v", err)
+	}
+	if fmt.Sprint(unpacked) != fmt.Sprint([]historyBucket(buckets)) {
+		t.Errorf("unpackHistoryBuckets() = %v, want %v", unpacked, buckets)
+	}
+
+	packed := packHistoryBuckets(buckets)
+	if _, err := unpackHistoryBuckets(packed[:len(packed)-1]); err == nil {
+		t.Error("Expected error for a truncated window, got nil")
+	}
+}
+
+// TestCheckpoint_FitsConfigMap fills every window for 7d at the shortest
+// monitor interval the configmap store accepts and checks that the
+// checkpoint stays within the bound and the ConfigMap limit
+func TestCheckpoint_FitsConfigMap(t *testing.T) {
+	config := Config{
+		CheckpointStore:         CheckpointStoreConfigMap,
+		PercentileWindow:        7 * 24 * time.Hour,
+		EnableMemoryUtilization: true,
+	}
+	for seconds := 1; seconds <= 60 && config.MonitorInterval == 0; seconds++ {
+		config.MonitorInterval = time.Duration(seconds) * time.Second
+		if checkpointMaxSize(config) > configMapMaxBytes {
+			config.MonitorInterval = 0
+		}
+	}
+	if config.MonitorInterval == 0 || config.MonitorInterval > 15*time.Second {
+		t.Fatalf("shortest interval for a 7d window is %s, want 15s or less", config.MonitorInterval)
+	}
+	config.MonitorInterval -= time.Second
+	if !strings.Contains(strings.Join(config.problems(), "\n"), "ConfigMap limit") {
+		t.Errorf("Expected a %s interval to be rejected for a 7d window", config.MonitorInterval)
+	}
+	config.MonitorInterval += time.Second
+
+	// Random bins barely compress
+	rng := rand.New(rand.NewSource(1))
+	now := time.Now()
+	var windows checkpointWindows
+	for _, buckets := range []*[]percentileBucket{&windows.cpu, &windows.memory, &windows.network} {
+		w := newPercentileWindow(config.PercentileWindow, 95, config.MonitorInterval)
+		for at := now.Add(-config.PercentileWindow); !at.After(now); at = at.Add(config.MonitorInterval) {
+			w.Add(rng.Float64()*100, at)
+		}
+		*buckets = w.Buckets()
+	}
+
+	data, err := encodeCheckpoint("node-a", windows, now)
+	if err != nil {
+		t.Fatalf("encodeCheckpoint() error = %v", err)
+	}
+	if len(data) > checkpointMaxSize(config) || len(data) > configMapMaxBytes {
+		t.Errorf("checkpoint at %s is %d bytes, bound %d, limit %d", config.MonitorInterval, len(data), checkpointMaxSize(config), configMapMaxBytes)
+	}
+	cp, err := decodeCheckpoint(data)
+	if err != nil {
+		t.Fatalf("decodeCheckpoint() error = %v", err)
+	}
+	if len(cp.Network) != len(windows.network) {
+		t.Errorf("decoded %d network buckets, want %d", len(cp.Network), len(windows.network))
+	}
+}
+
+func TestFindHistoryGaps(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	minute := start.Unix() / 60
+	buckets := []historyBucket{
+		{Minute: minute}, {Minute: minute + 1}, {Minute: minute + 2},
+		{Minute: minute + 30},
+		{Minute: minute + 31},
+	}
+
+	now := time.Unix((minute+32)*60+30, 0)
+	gaps := findHistoryGaps(buckets, now, 2*time.Minute)
+	if len(gaps) != 1 {
+		t.Fatalf("findHistoryGaps() = %v, want one gap", gaps)
+	}
+	if gaps[0].To.Sub(gaps[0].From) != 27*time.Minute {
+		t.Errorf("gap = %v, want 27m between minute 3 and minute 30", gaps[0].To.Sub(gaps[0].From))
+	}
+
+	// A long restart shows up as a gap up to now
+	gaps = findHistoryGaps(buckets, now.Add(time.Hour), 2*time.Minute)
+	if len(gaps) != 2 || !gaps[1].To.Equal(now.Add(time.Hour)) {
+		t.Errorf("findHistoryGaps() = %v, want a trailing gap up to now", gaps)
+	}
+
+	if historyMaxGap(30*time.Second) != 2*time.Minute || historyMaxGap(5*time.Minute) != 15*time.Minute {
+		t.Errorf("historyMaxGap() = %v / %v", historyMaxGap(30*time.Second), historyMaxGap(5*time.Minute))
+	}
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	store := &fileCheckpointStore{path: path}
+	ctx := context.Background()
+
+	if _, err := store.Load(ctx); !errors.Is(err, errNoCheckpoint) {
+		t.Errorf("Load() without a file error = %v, want errNoCheckpoint", err)
+	}
+
+	for _, content := range []string{"first", "second"} {
+		if err := store.Save(ctx, []byte(content)); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	data, err := store.Load(ctx)
+	if err != nil || string(data) != "second" {
+		t.Errorf("Load() = %q, %v, want the last save", data, err)
+	}
+
+	// No temporary files are left next to the checkpoint
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to list checkpoint dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("checkpoint dir has %d entries, want 1", len(entries))
+	}
+
+	if err := (&fileCheckpointStore{path: "/nonexistent/dir/history"}).Save(ctx, []byte("x")); err == nil {
+		t.Error("Expected error saving into a missing directory, got nil")
+	}
+}
+
+func TestConfigMapCheckpointStore(t *testing.T) {
+	config := Config{CheckpointStore: CheckpointStoreConfigMap, PodNamespace: "goburn", NodeName: "node-a"}
+	store, err := newCheckpointStore(config, fake.NewSimpleClientset())
+	if err != nil {
+		t.Fatalf("newCheckpointStore() error = %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := store.Load(ctx); !errors.Is(err, errNoCheckpoint) {
+		t.Errorf("Load() without a configmap error = %v, want errNoCheckpoint", err)
+	}
+
+	// The first save creates the configmap, the second updates it
+	for _, content := range []string{"first", "second"} {
+		if err := store.Save(ctx, []byte(content)); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	data, err := store.Load(ctx)
+	if err != nil || string(data) != "second" {
+		t.Errorf("Load() = %q, %v, want the last save", data, err)
+	}
+	if store.Name() != "configmap goburn/goburn-history-node-a" {
+		t.Errorf("Name() = %q", store.Name())
+	}
+	if err := store.Save(ctx, make([]byte, configMapMaxBytes+1)); err == nil {
+		t.Error("Expected error saving a checkpoint over the ConfigMap limit, got nil")
+	}
+
+	// Checkpoints from before version 3 are text data
+	legacy := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "goburn-history-node-b", Namespace: "goburn"},
+		Data:       map[string]string{checkpointConfigMapKey: "legacy"},
+	}
+	config.NodeName = "node-b"
+	store, _ = newCheckpointStore(config, fake.NewSimpleClientset(legacy))
+	if data, err := store.Load(ctx); err != nil || string(data) != "legacy" {
+		t.Errorf("Load() = %q, %v, want the text checkpoint", data, err)
+	}
+
+	if _, err := newCheckpointStore(Config{CheckpointStore: "s3"}, nil); err == nil {
+		t.Error("Expected error for unknown checkpoint store, got nil")
+	}
+}
+
+func TestResourceBurner_SaveAndRestoreHistory(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.cpuSamples = newPercentileWindow(time.Hour, 95, 30*time.Second)
+	store := &fileCheckpointStore{path: filepath.Join(t.TempDir(), "history")}
+	ctx := context.Background()
+
+	now := time.Now()
+	for i := 0; i < 20; i++ {
+		at := now.Add(time.Duration(i-20) * 30 * time.Second)
+		rb.cpuSamples.Add(float64(i*5), at)
+		rb.complianceReport(float64(i), float64(i*10), i%2 == 0, at)
+	}
+	if err := rb.saveHistory(ctx, store, now); err != nil {
+		t.Fatalf("saveHistory() error = %v", err)
+	}
+	want := rb.getCPUPercentile()
+
+	// A restarted pod starts cold and picks the samples back up
+	restarted := createTestResourceBurner(t)
+	restarted.cpuSamples = newPercentileWindow(time.Hour, 95, 30*time.Second)
+	restarted.restoreHistory(ctx, store, now.Add(time.Minute))
+	if restarted.cpuSamples.Count() != 20 || restarted.getCPUPercentile() != want {
+		t.Errorf("restored %d samples with p95 %.1f, want 20 with %.1f",
+			restarted.cpuSamples.Count(), restarted.getCPUPercentile(), want)
+	}
+	if memory := restarted.compliance.window(ResourceMemory); memory.Count() != 20 || memory.Value() != rb.compliance.window(ResourceMemory).Value() {
+		t.Errorf("restored %d memory samples, want 20 with the same percentile", memory.Count())
+	}
+	if network := restarted.compliance.window(ResourceNetwork); network.Count() != 10 || network.Value() != rb.compliance.window(ResourceNetwork).Value() {
+		t.Errorf("restored %d network samples, want 10 with the same percentile", network.Count())
+	}
+
+	// Network bins saved at another MAX_NETWORK_MBPS scale mean something else
+	rescaled := createTestResourceBurner(t)
+	rescaled.config.MaxNetworkMbps = 10000
+	rescaled.restoreHistory(ctx, store, now.Add(time.Minute))
+	if rescaled.compliance.window(ResourceNetwork).Count() != 0 || rescaled.compliance.window(ResourceMemory).Count() != 20 {
+		t.Error("Expected only the network history to be dropped after a scale change")
+	}
+
+	// Samples that aged out of the window while goburn was down are dropped
+	late := createTestResourceBurner(t)
+	late.cpuSamples = newPercentileWindow(time.Hour, 95, 30*time.Second)
+	late.restoreHistory(ctx, store, now.Add(2*time.Hour))
+	if late.cpuSamples.Count() != 0 {
+		t.Errorf("restored %d samples older than the window, want 0", late.cpuSamples.Count())
+	}
+}
+
+func TestResourceBurner_RestoreHistoryRejects(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history")
+	store := &fileCheckpointStore{path: path}
+	now := time.Now()
+
+	other, err := encodeCheckpoint("other-node", checkpointWindows{cpu: testHistoryBuckets(now)}, now)
+	if err != nil {
+		t.Fatalf("encodeCheckpoint() error = %v", err)
+	}
+
+	for name, content := range map[string][]byte{
+		"corrupt":    []byte("goburn-history 1 00000000\n{}"),
+		"other node": other,
+	} {
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatalf("failed to write checkpoint: %v", err)
+		}
+		rb := createTestResourceBurner(t)
+		rb.restoreHistory(ctx, store, now)
+		if rb.cpuSamples.Count() != 0 {
+			t.Errorf("%s: restored %d samples, want an empty window", name, rb.cpuSamples.Count())
+		}
+	}
+}
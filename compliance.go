@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// States of a compliance rule
+const (
+	ComplianceMet      = "met"
+	ComplianceAtRisk   = "at risk"
+	ComplianceViolated = "violated"
+)
+
+// Resources with a minimum rule
+const (
+	ResourceCPU     = "CPU"
+	ResourceMemory  = "Memory"
+	ResourceNetwork = "Network"
+)
+
+// complianceRule is one minimum: a percentile of a resource over the rolling
+// window must stay at or above a floor
+type complianceRule struct {
+	resource string
+	unit     string
+	minimum  float64
+	window   *percentileWindow
+}
+
+// ruleStatus is where a rule stands right now
+type ruleStatus struct {
+	Resource        string
+	Unit            string
+	Minimum         float64
+	Percentile      float64
+	Value           float64 // the percentile over the window
+	Mean            float64
+	Min             float64
+	Samples         int
+	State           string
+	TimeToViolation time.Duration // if every sample from now on is below the minimum
+}
+
+// complianceTracker keeps rolling-window statistics for the CPU, memory and
+// network minimums. The CPU window is shared with the percentile tracking and
+// the burst planner; all windows are guarded by cpuSampleMutex.
+type complianceTracker struct {
+	rules       []*complianceRule
+	riskHorizon time.Duration
+}
+
+func newComplianceTracker(config Config, cpuWindow *percentileWindow) *complianceTracker {
+	tracker := &complianceTracker{riskHorizon: config.ComplianceRiskHorizon}
+	tracker.rules = append(tracker.rules, &complianceRule{
+		resource: ResourceCPU, unit: "%", minimum: config.MinCPUUtilization, window: cpuWindow,
+	})
+	if config.EnableMemoryUtilization {
+		tracker.rules = append(tracker.rules, &complianceRule{
+			resource: ResourceMemory, unit: "%", minimum: config.MinMemoryUtilization,
+			window: newPercentileWindow(config.PercentileWindow, config.MemoryPercentile, config.MonitorInterval),
+		})
+	}
+
+	// Network is in Mbps, so its histogram spans up to the generator's maximum
+	network := newPercentileWindow(config.PercentileWindow, config.NetworkPercentile, config.MonitorInterval)
+	network.scale = math.Max(config.MaxNetworkMbps, 100)
+	tracker.rules = append(tracker.rules, &complianceRule{
+		resource: ResourceNetwork, unit: " Mbps", minimum: config.MinNetworkUtilizationMbps, window: network,
+	})
+	return tracker
+}
+
+// window returns the samples of a resource's rule, nil when it has none
+func (t *complianceTracker) window(resource string) *percentileWindow {
+	for _, rule := range t.rules {
+		if rule.resource == resource {
+			return rule.window
+		}
+	}
+	return nil
+}
+
+// Add records a sample of a resource other than CPU, which addCPUSample records
+func (t *complianceTracker) Add(resource string, value float64, now time.Time) {
+	for _, rule := range t.rules {
+		if rule.resource == resource && resource != ResourceCPU {
+			rule.window.Add(value, now)
+		}
+	}
+}
+
+// Report returns every rule, the closest to violation first
+func (t *complianceTracker) Report(now time.Time) []ruleStatus {
+	horizon := t.riskHorizon
+	if horizon <= 0 {
+		horizon = time.Hour
+	}
+
+	report := make([]ruleStatus, 0, len(t.rules))
+	for _, rule := range t.rules {
+		if rule.window.Count() == 0 {
+			continue // e.g. network counters that cannot be read
+		}
+		status := ruleStatus{
+			Resource:        rule.resource,
+			Unit:            rule.unit,
+			Minimum:         rule.minimum,
+			Percentile:      rule.window.Percentile(),
+			Value:           rule.window.Value(),
+			Mean:            rule.window.Mean(),
+			Min:             rule.window.Min(),
+			Samples:         rule.window.Count(),
+			TimeToViolation: rule.window.TimeToViolation(now, rule.minimum),
+		}
+		switch {
+		case status.Value < rule.minimum:
+			status.State = ComplianceViolated
+			status.TimeToViolation = 0
+		case status.TimeToViolation < horizon:
+			status.State = ComplianceAtRisk
+		default:
+			status.State = ComplianceMet
+		}
+		report = append(report, status)
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].TimeToViolation < report[j].TimeToViolation
+	})
+	return report
+}
+
+func (s ruleStatus) String() string {
+	text := fmt.Sprintf("%s %s (p%g %.1f%s, mean %.1f%s, min %.1f%s, floor %.1f%s",
+		s.Resource, s.State, s.Percentile, s.Value, s.Unit, s.Mean, s.Unit, s.Min, s.Unit, s.Minimum, s.Unit)
+	if s.State != ComplianceViolated {
+		text += fmt.Sprintf(", violation in %s", s.TimeToViolation.Round(time.Minute))
+	}
+	return text + ")"
+}
+
+// complianceReport records this tick's memory and network samples and
+// returns the state of every rule, most urgent first
+func (rb *ResourceBurner) complianceReport(memoryPercent, networkMbps float64, networkKnown bool, now time.Time) []ruleStatus {
+	rb.cpuSampleMutex.Lock()
+	defer rb.cpuSampleMutex.Unlock()
+
+	if rb.compliance == nil {
+		rb.compliance = newComplianceTracker(rb.config, rb.cpuSamples)
+	}
+	rb.compliance.Add(ResourceMemory, memoryPercent, now)
+	if networkKnown {
+		rb.compliance.Add(ResourceNetwork, networkMbps, now)
+	}
+	report := rb.compliance.Report(now)
+	if len(report) == 0 {
+		return report
+	}
+
+	parts := make([]string, len(report))
+	for i, status := range report {
+		parts[i] = status.String()
+	}
+	log.Printf("📋 Compliance - %s", strings.Join(parts, ", "))
+	return report
+}
+
+// Urgent reports whether a rule that is still met could be violated before
+// cooldown is over, which is worth skipping the cooldown for
+func (s ruleStatus) Urgent(cooldown time.Duration) bool {
+	return s.State == ComplianceAtRisk && s.TimeToViolation < cooldown
+}
+
+// enforceMinimums scales up the resources whose rule is not met, most urgent
+// first, and returns the resources it scaled. A violated rule is raised to its
+// minimum plus a buffer; a rule at risk only once the current sample is below
+// the minimum, since samples above it keep the rule where it is. Each resource
+// waits for its own cooldown unless its rule is urgent.
+func (rb *ResourceBurner) enforceMinimums(report []ruleStatus, cpuUtil, memUtil, networkUtil float64, networkKnown, burstCPU bool, now time.Time) map[string]bool {
+	enforced := make(map[string]bool)
+	for _, status := range report {
+		if status.State == ComplianceMet {
+			continue
+		}
+
+		var current, buffer float64
+		switch status.Resource {
+		case ResourceCPU:
+			if burstCPU {
+				continue // the burst planner owns the CPU minimum
+			}
+			current, buffer = cpuUtil, 10
+		case ResourceMemory:
+			if !rb.config.EnableMemoryUtilization {
+				continue
+			}
+			current, buffer = memUtil, 10
+		case ResourceNetwork:
+			if !networkKnown {
+				continue
+			}
+			current, buffer = networkUtil, 5
+		}
+
+		floor := status.Minimum
+		if status.State == ComplianceViolated {
+			floor += buffer
+		}
+		if current >= floor {
+			continue
+		}
+		if !rb.cooldownReady(status.Resource, now) {
+			if !status.Urgent(rb.config.ScaleUpDelay) {
+				continue
+			}
+			log.Printf("⏱️  %s minimum is %s and could be violated in %s - skipping its scaling cooldown",
+				status.Resource, status.State, status.TimeToViolation.Round(time.Second))
+		}
+
+		log.Printf("⚠️  %s minimum %s - p%g %.1f%s, current %.1f%s, floor %.1f%s - scaling up",
+			status.Resource, status.State, status.Percentile, status.Value, status.Unit,
+			current, status.Unit, status.Minimum, status.Unit)
+		switch status.Resource {
+		case ResourceCPU:
+			rb.adjustCPULoad(status.Minimum+buffer, current)
+		case ResourceMemory:
+			rb.adjustMemoryLoad(status.Minimum+buffer, current)
+		case ResourceNetwork:
+			rb.adjustNetworkLoad(status.Minimum+buffer, current)
+		}
+		rb.cooldown(status.Resource).Record(now, true)
+		enforced[status.Resource] = true
+	}
+	return enforced
+}
+
+// reconfigure applies reloaded minimums, percentiles and windows while keeping
+// the samples; the CPU window is updated by the caller
+func (t *complianceTracker) reconfigure(config Config) {
+	t.riskHorizon = config.ComplianceRiskHorizon
+
+	// Memory can be switched on and off by node overrides
+	rules := t.rules[:0]
+	hasMemory := false
+	for _, rule := range t.rules {
+		if rule.resource == ResourceMemory {
+			if !config.EnableMemoryUtilization {
+				continue
+			}
+			hasMemory = true
+		}
+		rules = append(rules, rule)
+	}
+	t.rules = rules
+	if config.EnableMemoryUtilization && !hasMemory {
+		t.rules = append(t.rules, &complianceRule{
+			resource: ResourceMemory, unit: "%",
+			window: newPercentileWindow(config.PercentileWindow, config.MemoryPercentile, config.MonitorInterval),
+		})
+	}
+
+	for _, rule := range t.rules {
+		switch rule.resource {
+		case ResourceCPU:
+			rule.minimum = config.MinCPUUtilization
+			continue
+		case ResourceMemory:
+			rule.minimum = config.MinMemoryUtilization
+			rule.window.percentile = config.MemoryPercentile
+		case ResourceNetwork:
+			rule.minimum = config.MinNetworkUtilizationMbps
+			rule.window.percentile = config.NetworkPercentile
+		}
+		rule.window.window = config.PercentileWindow
+		rule.window.sampleInterval = config.MonitorInterval
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fillWindow adds one sample per interval for an hour starting at the epoch,
+// taking values from valueAt, and returns the time of the last sample
+func fillWindow(w *percentileWindow, valueAt func(minute int) float64) time.Time {
+	start := time.Unix(0, 0)
+	var last time.Time
+	for i := 0; i < 120; i++ {
+		last = start.Add(time.Duration(i) * 30 * time.Second)
+		w.Add(valueAt(i/2), last)
+	}
+	return last
+}
+
+func TestPercentileWindowMeanMin(t *testing.T) {
+	w := newPercentileWindow(time.Hour, 95, 30*time.Second)
+	if w.Mean() != 0 || w.Min() != 0 {
+		t.Errorf("Expected an empty window to report 0, got mean %.1f min %.1f", w.Mean(), w.Min())
+	}
+
+	now := time.Unix(0, 0)
+	for _, v := range []float64{10, 20, 30, 40} {
+		w.Add(v, now)
+	}
+	if w.Mean() != 25 {
+		t.Errorf("Expected mean 25, got %.2f", w.Mean())
+	}
+	if w.Min() != 10 {
+		t.Errorf("Expected min 10, got %.2f", w.Min())
+	}
+
+	// Values that are not percentages keep their unit
+	network := newPercentileWindow(time.Hour, 95, 30*time.Second)
+	network.scale = 1000
+	network.Add(500, now)
+	network.Add(2000, now)
+	if network.Min() != 500 || network.Value() != 1000 {
+		t.Errorf("Expected min 500 and p95 1000 (capped at the scale), got %.1f and %.1f", network.Min(), network.Value())
+	}
+}
+
+func TestPercentileWindowTimeToViolation(t *testing.T) {
+	w := newPercentileWindow(time.Hour, 95, 30*time.Second)
+	now := fillWindow(w, func(int) float64 { return 50 })
+
+	// 120 samples at 50%: two high samples expire each minute while two low
+	// ones come in, and p95 of 122 samples needs 7 high ones, so the rule
+	// holds until minute 58
+	if got := w.TimeToViolation(now, 20); got != 58*time.Minute {
+		t.Errorf("Expected violation in 58m, got %s", got)
+	}
+
+	if got := w.TimeToViolation(now, 60); got != 0 {
+		t.Errorf("Expected an already violated rule to report 0, got %s", got)
+	}
+
+	// Only the first five minutes were high: they are about to expire
+	w.Reset()
+	now = fillWindow(w, func(minute int) float64 {
+		if minute < 5 {
+			return 50
+		}
+		return 10
+	})
+	if got := w.TimeToViolation(now, 20); got != 3*time.Minute {
+		t.Errorf("Expected violation in 3m, got %s", got)
+	}
+}
+
+func TestComplianceTracker_Report(t *testing.T) {
+	config := Config{
+		MinCPUUtilization:         20,
+		MinMemoryUtilization:      20,
+		MinNetworkUtilizationMbps: 20,
+		EnableMemoryUtilization:   true,
+		MaxNetworkMbps:            1000,
+		PercentileWindow:          time.Hour,
+		MonitorInterval:           30 * time.Second,
+		ComplianceRiskHorizon:     30 * time.Minute,
+	}
+	cpu := newPercentileWindow(config.PercentileWindow, 95, config.MonitorInterval)
+	tracker := newComplianceTracker(config, cpu)
+
+	now := fillWindow(cpu, func(int) float64 { return 50 })
+	start := time.Unix(0, 0)
+	for i := 0; i < 120; i++ {
+		at := start.Add(time.Duration(i) * 30 * time.Second)
+		tracker.Add(ResourceMemory, 10, at)
+		network := 10.0
+		if i < 10 {
+			network = 300
+		}
+		tracker.Add(ResourceNetwork, network, at)
+		// CPU samples go through the shared window only
+		tracker.Add(ResourceCPU, 0, at)
+	}
+
+	report := tracker.Report(now)
+	if len(report) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(report))
+	}
+
+	expected := []struct {
+		resource string
+		state    string
+	}{
+		{ResourceMemory, ComplianceViolated},
+		{ResourceNetwork, ComplianceAtRisk},
+		{ResourceCPU, ComplianceMet},
+	}
+	for i, want := range expected {
+		got := report[i]
+		if got.Resource != want.resource || got.State != want.state {
+			t.Errorf("Rule %d: expected %s %s, got %s %s", i, want.resource, want.state, got.Resource, got.State)
+		}
+	}
+
+	if report[0].TimeToViolation != 0 {
+		t.Errorf("Expected the violated rule to report 0, got %s", report[0].TimeToViolation)
+	}
+	if network := report[1]; network.Value != 300 || abs(network.Mean-4100.0/120) > 0.01 || network.Min != 10 {
+		t.Errorf("Expected network p95 300, mean 34.2 and min 10 Mbps, got %.1f, %.1f and %.1f",
+			network.Value, network.Mean, network.Min)
+	}
+	if cpu.Count() != 120 {
+		t.Errorf("Expected the CPU window to be left alone, got %d samples", cpu.Count())
+	}
+}
+
+func TestComplianceTracker_NoSamples(t *testing.T) {
+	config := Config{MinNetworkUtilizationMbps: 20, MonitorInterval: 30 * time.Second}
+	tracker := newComplianceTracker(config, newPercentileWindow(time.Hour, 95, config.MonitorInterval))
+
+	// A resource that cannot be measured is not reported as violated
+	if report := tracker.Report(time.Unix(0, 0)); len(report) != 0 {
+		t.Errorf("Expected no rules without samples, got %v", report)
+	}
+}
+
+func TestRuleStatusUrgent(t *testing.T) {
+	tests := []struct {
+		status ruleStatus
+		urgent bool
+	}{
+		{ruleStatus{State: ComplianceViolated}, false},
+		{ruleStatus{State: ComplianceAtRisk, TimeToViolation: 30 * time.Second}, true},
+		{ruleStatus{State: ComplianceAtRisk, TimeToViolation: 10 * time.Minute}, false},
+		{ruleStatus{State: ComplianceMet, TimeToViolation: 30 * time.Second}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.status.Urgent(time.Minute); got != tt.urgent {
+			t.Errorf("%s in %s: expected urgent %v, got %v", tt.status.State, tt.status.TimeToViolation, tt.urgent, got)
+		}
+	}
+}
+
+func TestEnforceMinimums(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	defer rb.traffic.Stop()
+
+	report := []ruleStatus{
+		{Resource: ResourceNetwork, State: ComplianceViolated, Minimum: 20},
+		{Resource: ResourceMemory, State: ComplianceAtRisk, Minimum: 20},
+		{Resource: ResourceCPU, State: ComplianceViolated, Minimum: 20},
+	}
+
+	// Memory is at risk but still above its minimum, and CPU belongs to the burst planner
+	now := time.Unix(0, 0)
+	enforced := rb.enforceMinimums(report, 5, 25, 0, true, true, now)
+	if len(enforced) != 1 || !enforced[ResourceNetwork] {
+		t.Fatalf("Expected only the network minimum to be enforced, got %v", enforced)
+	}
+	if rb.traffic.Rate() == 0 {
+		t.Error("Expected network traffic to be scaled up")
+	}
+	if rb.memory.SizeMB() != 0 {
+		t.Errorf("Expected memory at risk above its minimum to be left alone, got %d MB", rb.memory.SizeMB())
+	}
+	if rb.cpuWorkers != 0 {
+		t.Errorf("Expected CPU to be left to the burst planner, got %d workers", rb.cpuWorkers)
+	}
+
+	// The network now waits for its cooldown, unless its rule is urgent
+	if enforced := rb.enforceMinimums(report[:1], 5, 25, 0, true, true, now.Add(time.Second)); len(enforced) != 0 {
+		t.Errorf("Expected the network to wait for its cooldown, got %v", enforced)
+	}
+	urgent := []ruleStatus{{Resource: ResourceNetwork, State: ComplianceAtRisk, Minimum: 20, TimeToViolation: 10 * time.Second}}
+	if enforced := rb.enforceMinimums(urgent, 5, 25, 0, true, true, now.Add(time.Second)); !enforced[ResourceNetwork] {
+		t.Error("Expected an urgent rule to skip the cooldown")
+	}
+
+	// Nothing to do once every rule is met
+	met := []ruleStatus{{Resource: ResourceCPU, State: ComplianceMet, Minimum: 20}}
+	if enforced := rb.enforceMinimums(met, 5, 5, 0, true, false, now); len(enforced) != 0 {
+		t.Errorf("Expected met rules not to be enforced, got %v", enforced)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// validateSubcommand checks the configuration from the environment and exits
+const validateSubcommand = "validate"
+
+// configError lists every problem found in a configuration
+type configError struct {
+	problems []string
+}
+
+func (e *configError) Error() string {
+	return fmt.Sprintf("invalid configuration (%d problems):\n  - %s", len(e.problems), strings.Join(e.problems, "\n  - "))
+}
+
+// Validate rejects settings that are out of range or contradict each other,
+// listing all of them rather than stopping at the first
+func (c Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &configError{problems: problems}
+	}
+	return nil
+}
+
+func (c Config) problems() []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	percent := func(name string, value float64) {
+		if value < 0 || value > 100 {
+			add("%s (%g) must be between 0 and 100", name, value)
+		}
+	}
+	percentile := func(name string, value float64) {
+		if value <= 0 || value > 100 {
+			add("%s (%g) must be above 0 and at most 100", name, value)
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		add("%s %q is not one of %s", name, value, strings.Join(allowed, ", "))
+	}
+
+	// Targets and minimums
+	percent("TARGET_CPU_UTILIZATION", c.TargetCPUUtilization)
+	percent("TARGET_MEMORY_UTILIZATION", c.TargetMemoryUtilization)
+	percent("MIN_CPU_UTILIZATION", c.MinCPUUtilization)
+	percent("MIN_MEMORY_UTILIZATION", c.MinMemoryUtilization)
+	if c.MinCPUUtilization > c.TargetCPUUtilization {
+		add("MIN_CPU_UTILIZATION (%g) is above TARGET_CPU_UTILIZATION (%g)", c.MinCPUUtilization, c.TargetCPUUtilization)
+	}
+	if c.EnableMemoryUtilization && c.MinMemoryUtilization > c.TargetMemoryUtilization {
+		add("MIN_MEMORY_UTILIZATION (%g) is above TARGET_MEMORY_UTILIZATION (%g)", c.MinMemoryUtilization, c.TargetMemoryUtilization)
+	}
+	if c.MinNetworkUtilizationMbps < 0 {
+		add("MIN_NETWORK_UTILIZATION_MBPS (%g) must not be negative", c.MinNetworkUtilizationMbps)
+	}
+	if c.MaxNetworkMbps <= 0 {
+		add("MAX_NETWORK_MBPS (%g) must be positive", c.MaxNetworkMbps)
+	} else if c.MinNetworkUtilizationMbps > c.MaxNetworkMbps {
+		add("MIN_NETWORK_UTILIZATION_MBPS (%g) is above MAX_NETWORK_MBPS (%g)", c.MinNetworkUtilizationMbps, c.MaxNetworkMbps)
+	}
+
+	// Timing
+	if c.MonitorInterval <= 0 {
+		add("MONITOR_INTERVAL_SECONDS (%s) must be positive", c.MonitorInterval)
+	}
+	if c.ScaleUpDelay < 0 {
+		add("SCALE_UP_DELAY_SECONDS (%s) must not be negative", c.ScaleUpDelay)
+	}
+	if c.ScaleDownDelay < 0 {
+		add("SCALE_DOWN_DELAY_SECONDS (%s) must not be negative", c.ScaleDownDelay)
+	}
+	if c.MetricsMaxAge <= 0 {
+		add("METRICS_MAX_AGE_SECONDS (%s) must be positive", c.MetricsMaxAge)
+	}
+	if c.CPUDutyPeriod <= 0 {
+		add("CPU_DUTY_PERIOD_MS (%s) must be positive", c.CPUDutyPeriod)
+	}
+	if c.EmergencyQuietPeriod < 0 {
+		add("EMERGENCY_QUIET_SECONDS (%s) must not be negative", c.EmergencyQuietPeriod)
+	}
+	if c.ComplianceRiskHorizon < 0 {
+		add("COMPLIANCE_RISK_HORIZON (%s) must not be negative", c.ComplianceRiskHorizon)
+	}
+	if c.MonitorInterval > 0 && c.PercentileWindow < c.MonitorInterval {
+		add("PERCENTILE_WINDOW (%s) is shorter than MONITOR_INTERVAL_SECONDS (%s)", c.PercentileWindow, c.MonitorInterval)
+	}
+	percentile("CPU_PERCENTILE", c.CPUPercentile)
+	percentile("MEMORY_PERCENTILE", c.MemoryPercentile)
+	percentile("NETWORK_PERCENTILE", c.NetworkPercentile)
+
+	// Memory
+	if c.MaxMemoryMB <= 0 {
+		add("MAX_MEMORY_MB (%d) must be positive", c.MaxMemoryMB)
+	} else if total, _, err := readMemInfo(); err == nil && c.MaxMemoryMB > total/(1024*1024) {
+		add("MAX_MEMORY_MB (%d) is larger than the node's memory (%d MB)", c.MaxMemoryMB, total/(1024*1024))
+	}
+	oneOf("MEMORY_FILL", c.MemoryFill, MemoryFillZero, MemoryFillRandom, MemoryFillRatio)
+	if c.MemoryFill == MemoryFillRatio && c.MemoryFillRatio < 1 {
+		add("MEMORY_FILL_RATIO (%g) must be at least 1", c.MemoryFillRatio)
+	}
+	percent("MIN_MEM_AVAILABLE_PERCENT", c.MinMemAvailablePercent)
+	percent("MEMORY_PRESSURE_THRESHOLD", c.MemoryPressureThreshold)
+
+	// CPU
+	oneOf("CONTROLLER", c.Controller, ControllerStep, ControllerPID)
+	oneOf("CPU_PRIORITY", c.CPUPriority, CPUPriorityNormal, CPUPriorityIdle, CPUPriorityNice)
+	if c.CPUPriority == CPUPriorityNice && (c.CPUNice < 0 || c.CPUNice > 19) {
+		add("CPU_NICE (%d) must be between 0 and 19", c.CPUNice)
+	}
+	oneOf("CPU_STRATEGY", c.CPUStrategy, CPUStrategyContinuous, CPUStrategyBurst)
+	if c.BurstMargin < 0 {
+		add("BURST_MARGIN (%g) must not be negative", c.BurstMargin)
+	}
+	if c.BurstHeadroom < 0 {
+		add("BURST_HEADROOM (%g) must not be negative", c.BurstHeadroom)
+	}
+	percent("CPU_PRESSURE_THRESHOLD", c.CPUPressureThreshold)
+	if c.CPUPressureReleaseMillicores < 0 {
+		add("CPU_PRESSURE_RELEASE_MILLICORES (%d) must not be negative", c.CPUPressureReleaseMillicores)
+	}
+	percent("EMERGENCY_JUMP_PERCENT", c.EmergencyJumpPercent)
+	percent("EMERGENCY_CEILING_PERCENT", c.EmergencyCeilingPercent)
+	for _, pid := range []struct {
+		prefix string
+		gains  PIDGains
+	}{{"PID_CPU", c.CPUGains}, {"PID_MEMORY", c.MemoryGains}, {"PID_NETWORK", c.NetworkGains}} {
+		if g := pid.gains; g.Kp < 0 || g.Ki < 0 || g.Kd < 0 {
+			add("%s_KP, _KI and _KD (%g, %g, %g) must not be negative", pid.prefix, g.Kp, g.Ki, g.Kd)
+		}
+	}
+	if c.BurnCgroup && (c.BurnCPUWeight < 1 || c.BurnCPUWeight > 10000) {
+		add("BURN_CPU_WEIGHT (%d) must be between 1 and 10000", c.BurnCPUWeight)
+	}
+	if c.BurnMemoryHighMB < 0 {
+		add("BURN_MEMORY_HIGH_MB (%d) must not be negative", c.BurnMemoryHighMB)
+	}
+
+	// Network and peers
+	oneOf("NETWORK_MODE", c.NetworkMode, NetworkModeUDP, NetworkModeTCP, NetworkModeLoopback)
+	if c.NetworkStreams < 1 {
+		add("NETWORK_STREAMS (%d) must be at least 1", c.NetworkStreams)
+	}
+	if c.PeerLabelSelector != "" {
+		if c.PeerPort < 1 || c.PeerPort > 65535 {
+			add("PEER_PORT (%d) must be between 1 and 65535", c.PeerPort)
+		}
+		if c.MaxPeers < 1 {
+			add("MAX_PEERS (%d) must be at least 1", c.MaxPeers)
+		}
+		if c.PeerRefreshInterval <= 0 {
+			add("PEER_REFRESH_SECONDS (%s) must be positive", c.PeerRefreshInterval)
+		}
+	}
+	if len(c.MetricsSources) == 0 {
+		add("METRICS_SOURCES must name at least one source")
+	}
+	for _, source := range c.MetricsSources {
+		oneOf("METRICS_SOURCES entry", source, MetricsSourceMetricsServer, MetricsSourceKubelet, MetricsSourceProcfs)
+	}
+
+	// History
+	if c.CheckpointStore != "" {
+		oneOf("CHECKPOINT_STORE", c.CheckpointStore, CheckpointStoreFile, CheckpointStoreConfigMap)
+		if c.CheckpointInterval <= 0 {
+			add("CHECKPOINT_INTERVAL_SECONDS (%s) must be positive", c.CheckpointInterval)
+		}
+		if c.CheckpointStore == CheckpointStoreFile && c.CheckpointPath == "" {
+			add("CHECKPOINT_PATH must be set for the file checkpoint store")
+		}
+		if c.CheckpointStore == CheckpointStoreConfigMap && c.MonitorInterval > 0 {
+			if size := checkpointMaxSize(c); size > configMapMaxBytes {
+				add("PERCENTILE_WINDOW (%s) at MONITOR_INTERVAL_SECONDS (%s) can need a %d KB checkpoint, over the %d KB ConfigMap limit",
+					c.PercentileWindow, c.MonitorInterval, size/1024, configMapMaxBytes/1024)
+			}
+		}
+	}
+
+	if c.NodeName == "" {
+		add("NODE_NAME is empty")
+	}
+	return problems
+}
+
+// runValidate loads the configuration like the burner would, prints the
+// result to out and returns the exit code for `goburn validate`
+func runValidate(out io.Writer, sources configSources) int {
+	config, err := loadConfig(sources)
+	if err != nil {
+		fmt.Fprintf(out, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "✅ Configuration is valid for node %s\n", config.NodeName)
+	fmt.Fprintf(out, "   Targets - CPU: %.1f%%, Memory: %.1f%%\n", config.TargetCPUUtilization, config.TargetMemoryUtilization)
+	fmt.Fprintf(out, "   Minimums - CPU p%g over %s: %.1f%%, Memory: %.1f%%, Network: %.1f Mbps\n",
+		config.CPUPercentile, config.PercentileWindow, config.MinCPUUtilization, config.MinMemoryUtilization, config.MinNetworkUtilizationMbps)
+	fmt.Fprintf(out, "   Controller: %s, CPU strategy: %s, Network mode: %s, Max memory: %d MB\n",
+		config.Controller, config.CPUStrategy, config.NetworkMode, config.MaxMemoryMB)
+	return 0
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// configReloadInterval is how often the config file is checked for changes.
+// A mounted ConfigMap is updated by swapping a symlink, which file watches
+// tend to miss, so the file is simply read again.
+const configReloadInterval = 10 * time.Second
+
+// configSources are the places settings come from besides the environment:
+// the config file (--config or CONFIG_FILE), which the environment overrides,
+// --set KEY=VALUE flags, which override both, and the goburn.io/ labels and
+// annotations of the node, which override everything
+type configSources struct {
+	file  string
+	flags map[string]string
+	node  map[string]string
+}
+
+// configReload is a new configuration for the monitor and what it came from
+type configReload struct {
+	config Config
+	source string
+}
+
+// args renders the file and flags as command line flags, for the burner
+// child. Node overrides only change what the control loop asks of it.
+func (s configSources) args() []string {
+	var args []string
+	if s.file != "" {
+		args = append(args, "--config", s.file)
+	}
+	keys := make([]string, 0, len(s.flags))
+	for key := range s.flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--set", key+"="+s.flags[key])
+	}
+	return args
+}
+
+// setFlags collects repeated --set KEY=VALUE flags
+type setFlags map[string]string
+
+func (f setFlags) String() string {
+	return fmt.Sprint(map[string]string(f))
+}
+
+func (f setFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected KEY=VALUE, got %q", value)
+	}
+	f[strings.TrimSpace(key)] = val
+	return nil
+}
+
+// registerConfigFlags adds --config and --set to fs and returns the sources
+// they fill in once fs is parsed
+func registerConfigFlags(fs *flag.FlagSet) *configSources {
+	sources := &configSources{flags: make(map[string]string)}
+	fs.StringVar(&sources.file, "config", "", "YAML or JSON config file, overrides CONFIG_FILE")
+	fs.Var(setFlags(sources.flags), "set", "override a setting, e.g. --set TARGET_CPU_UTILIZATION=70 (repeatable)")
+	return sources
+}
+
+// readConfigFile reads a YAML or JSON file of settings keyed by their
+// environment variable names. Lists are joined with commas, so they read
+// like the environment variable would.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigFile(data)
+}
+
+func parseConfigFile(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %v", err)
+	}
+
+	settings := make(map[string]string, len(raw))
+	for key, value := range raw {
+		text, err := configValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", key, err)
+		}
+		settings[key] = text
+	}
+	return settings, nil
+}
+
+func configValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			text, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = text
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("expected a single value or a list, got %T", value)
+	}
+}
+
+// restartOnlySettings are set up once when goburn starts: workers, streams,
+// sources and cgroups that would have to be torn down to change them. A
+// reload keeps their old values and says so.
+var restartOnlySettings = []struct {
+	field string
+	key   string
+}{
+	{"ConfigFile", "CONFIG_FILE"},
+	{"NodeName", "NODE_NAME"},
+	{"NetworkInterface", "NETWORK_INTERFACE"},
+	{"NetworkMode", "NETWORK_MODE"},
+	{"NetworkTarget", "NETWORK_TARGET"},
+	{"NetworkStreams", "NETWORK_STREAMS"},
+	{"MetricsSources", "METRICS_SOURCES"},
+	{"MetricsMaxAge", "METRICS_MAX_AGE_SECONDS"},
+	{"SinkAddr", "SINK_ADDR"},
+	{"PeerLabelSelector", "PEER_LABEL_SELECTOR"},
+	{"PodNamespace", "POD_NAMESPACE"},
+	{"PodName", "POD_NAME"},
+	{"PeerPort", "PEER_PORT"},
+	{"MaxPeers", "MAX_PEERS"},
+	{"PeerRefreshInterval", "PEER_REFRESH_SECONDS"},
+	{"CPUPriority", "CPU_PRIORITY"},
+	{"CPUNice", "CPU_NICE"},
+	{"MemoryFill", "MEMORY_FILL"},
+	{"MemoryFillRatio", "MEMORY_FILL_RATIO"},
+	{"MemoryGuard", "MEMORY_GUARD"},
+	{"CheckpointStore", "CHECKPOINT_STORE"},
+	{"CheckpointPath", "CHECKPOINT_PATH"},
+	{"CheckpointInterval", "CHECKPOINT_INTERVAL_SECONDS"},
+	{"BurnCgroup", "BURN_CGROUP"},
+	{"BurnCPUWeight", "BURN_CPU_WEIGHT"},
+	{"BurnMemoryHighMB", "BURN_MEMORY_HIGH_MB"},
+}
+
+// mergeReload returns next with the restart-only settings of current, the
+// names of the settings that changed live and of those that need a restart
+func mergeReload(current, next Config) (merged Config, changed, restart []string) {
+	cur := reflect.ValueOf(current)
+	nxt := reflect.ValueOf(&next).Elem()
+
+	for _, setting := range restartOnlySettings {
+		old, updated := cur.FieldByName(setting.field), nxt.FieldByName(setting.field)
+		if !reflect.DeepEqual(old.Interface(), updated.Interface()) {
+			restart = append(restart, setting.key)
+			updated.Set(old)
+		}
+	}
+
+	for i := 0; i < cur.NumField(); i++ {
+		if !reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			changed = append(changed, cur.Type().Field(i).Name)
+		}
+	}
+	return next, changed, restart
+}
+
+// currentConfig returns the configuration for goroutines other than the
+// monitor, which is the only one that replaces it
+func (rb *ResourceBurner) currentConfig() Config {
+	rb.configMutex.RLock()
+	defer rb.configMutex.RUnlock()
+	return rb.config
+}
+
+// applyConfig switches the running burner to a reloaded configuration. It runs
+// on the monitor goroutine between ticks; workers keep running and pick the
+// new limits up on their next adjustment.
+func (rb *ResourceBurner) applyConfig(reload configReload, ticker *time.Ticker) {
+	next, changed, restart := mergeReload(rb.config, reload.config)
+	for _, key := range restart {
+		log.Printf("⚠️  %s changed in %s but only takes effect after a restart", key, reload.source)
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	previous := rb.config
+	rb.configMutex.Lock()
+	rb.config = next
+	rb.configMutex.Unlock()
+	log.Printf("🔄 Applied config from %s: %s", reload.source, strings.Join(changed, ", "))
+
+	if ticker != nil && next.MonitorInterval != previous.MonitorInterval {
+		ticker.Reset(next.MonitorInterval)
+	}
+
+	rb.cpuSampleMutex.Lock()
+	rb.cpuSamples.window = next.PercentileWindow
+	rb.cpuSamples.percentile = next.CPUPercentile
+	rb.cpuSamples.sampleInterval = next.MonitorInterval
+	if rb.compliance != nil {
+		rb.compliance.reconfigure(next)
+	}
+	rb.cpuSampleMutex.Unlock()
+
+	// Running workers keep the duty period they started with
+	if next.CPUDutyPeriod != previous.CPUDutyPeriod {
+		rb.cpuMutex.Lock()
+		if workers := rb.cpuWorkers; workers > 0 && rb.burnChild == nil {
+			rb.setCPUWorkersLocked(0)
+			rb.setCPUWorkersLocked(workers)
+		}
+		rb.cpuMutex.Unlock()
+	}
+
+	if rb.pid != nil {
+		rb.pid.cpu.gains = next.CPUGains
+		rb.pid.memory.gains = next.MemoryGains
+		rb.pid.network.gains = next.NetworkGains
+		rb.pid.network.outMax = next.MaxNetworkMbps
+	}
+
+	// Memory switched off gives back everything it held
+	if previous.EnableMemoryUtilization && !next.EnableMemoryUtilization {
+		rb.releaseMemoryLoad()
+		if rb.pid != nil {
+			rb.pid.memory.Reset()
+		}
+	}
+
+	// The burn cgroup follows a changed MAX_MEMORY_MB
+	if rb.burnChild != nil {
+		if high := burnMemoryHighMB(next); high != burnMemoryHighMB(previous) {
+			if err := rb.burnChild.setMemoryHigh(high); err != nil {
+				log.Printf("⚠️  Cannot update memory.high of the burn cgroup: %v", err)
+			}
+		}
+	}
+
+	// Lower caps apply right away
+	if size := rb.memorySizeMB(); size > next.MaxMemoryMB {
+		rb.setMemoryMB(next.MaxMemoryMB)
+	}
+	rb.networkMutex.Lock()
+	if rate := rb.traffic.Rate(); rate > next.MaxNetworkMbps {
+		rb.traffic.SetRate(next.MaxNetworkMbps)
+	}
+	rb.networkMutex.Unlock()
+}
+
+// configWorker reads the config file every configReloadInterval and the
+// node's overrides every nodeOverrideInterval, and hands a changed, valid
+// configuration to the monitor. An invalid change is logged and the running
+// configuration kept.
+func (rb *ResourceBurner) configWorker(ctx context.Context, sources configSources, overrides *nodeOverrideReader) {
+	var last []byte
+	var fileTicks, nodeTicks <-chan time.Time
+	if sources.file != "" {
+		var err error
+		if last, err = os.ReadFile(sources.file); err != nil {
+			log.Printf("⚠️  Cannot read config file %s: %v", sources.file, err)
+		}
+		ticker := time.NewTicker(configReloadInterval)
+		defer ticker.Stop()
+		fileTicks = ticker.C
+	}
+	if overrides != nil {
+		ticker := time.NewTicker(nodeOverrideInterval)
+		defer ticker.Stop()
+		nodeTicks = ticker.C
+	}
+
+	for {
+		var source string
+		select {
+		case <-ctx.Done():
+			return
+		case <-fileTicks:
+			data, err := os.ReadFile(sources.file)
+			if err != nil {
+				log.Printf("⚠️  Cannot read config file %s, keeping the running config: %v", sources.file, err)
+				continue
+			}
+			if bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			source = sources.file
+		case <-nodeTicks:
+			if !overrides.Refresh(ctx, &sources) {
+				continue
+			}
+			source = "node " + overrides.nodeName
+		}
+
+		config, err := loadConfig(sources)
+		if err != nil {
+			log.Printf("⚠️  Ignoring config change from %s, keeping the running config: %v", source, err)
+			continue
+		}
+
+		// Only the latest change matters if the monitor has not picked one up yet
+		select {
+		case <-rb.configReloads:
+		default:
+		}
+		rb.configReloads <- configReload{config: config, source: source}
+	}
+}
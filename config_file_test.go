@@ -0,0 +1,238 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"yaml", `
+TARGET_CPU_UTILIZATION: 70
+PID_CPU_KI: 0.02
+ENABLE_MEMORY_UTILIZATION: false
+PERCENTILE_WINDOW: 7d
+METRICS_SOURCES: [procfs, kubelet]
+NETWORK_TARGET:
+`},
+		{"json", `{"TARGET_CPU_UTILIZATION": 70, "PID_CPU_KI": 0.02, "ENABLE_MEMORY_UTILIZATION": false,
+"PERCENTILE_WINDOW": "7d", "METRICS_SOURCES": ["procfs", "kubelet"], "NETWORK_TARGET": null}`},
+	}
+
+	expected := map[string]string{
+		"TARGET_CPU_UTILIZATION":    "70",
+		"PID_CPU_KI":                "0.02",
+		"ENABLE_MEMORY_UTILIZATION": "false",
+		"PERCENTILE_WINDOW":         "7d",
+		"METRICS_SOURCES":           "procfs,kubelet",
+		"NETWORK_TARGET":            "",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := parseConfigFile([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseConfigFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(settings, expected) {
+				t.Errorf("parseConfigFile() = %v, want %v", settings, expected)
+			}
+		})
+	}
+
+	if _, err := parseConfigFile([]byte("PID_CPU:\n  KP: 1\n")); err == nil {
+		t.Error("Expected nested settings to be rejected")
+	}
+	if _, err := parseConfigFile([]byte("- just a list")); err == nil {
+		t.Error("Expected a file that is not a map to be rejected")
+	}
+}
+
+// writeConfigFile writes a config file into a temporary directory
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 8388608 kB\nMemAvailable: 4194304 kB\n"})
+	path := writeConfigFile(t, `
+NODE_NAME: file-node
+TARGET_CPU_UTILIZATION: 70
+MIN_CPU_UTILIZATION: 25
+SCALE_UP_DELAY_SECONDS: 30
+`)
+
+	os.Setenv("TARGET_CPU_UTILIZATION", "75")
+	os.Setenv("SCALE_UP_DELAY_SECONDS", "45")
+	defer os.Unsetenv("TARGET_CPU_UTILIZATION")
+	defer os.Unsetenv("SCALE_UP_DELAY_SECONDS")
+
+	// File, then environment, then flags
+	sources := configSources{file: path, flags: map[string]string{"TARGET_CPU_UTILIZATION": "60"}}
+	config, err := loadConfig(sources)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	if config.TargetCPUUtilization != 60 {
+		t.Errorf("TargetCPUUtilization = %v, want 60 from --set", config.TargetCPUUtilization)
+	}
+	if config.ScaleUpDelay != 45*time.Second {
+		t.Errorf("ScaleUpDelay = %v, want 45s from the environment", config.ScaleUpDelay)
+	}
+	if config.MinCPUUtilization != 25 || config.NodeName != "file-node" {
+		t.Errorf("MinCPUUtilization = %v, NodeName = %q, want 25 and file-node from the file",
+			config.MinCPUUtilization, config.NodeName)
+	}
+	if config.ConfigFile != path {
+		t.Errorf("ConfigFile = %q, want %q", config.ConfigFile, path)
+	}
+
+	// CONFIG_FILE names the file when --config does not
+	os.Setenv("CONFIG_FILE", path)
+	defer os.Unsetenv("CONFIG_FILE")
+	if config, err := loadConfig(configSources{}); err != nil || config.MinCPUUtilization != 25 {
+		t.Errorf("loadConfig() with CONFIG_FILE = %v, %v, want MinCPUUtilization 25", config.MinCPUUtilization, err)
+	}
+}
+
+func TestLoadConfig_UnknownSettings(t *testing.T) {
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 8388608 kB\nMemAvailable: 4194304 kB\n"})
+	path := writeConfigFile(t, "NODE_NAME: test-node\nTARGET_CPU_UTILISATION: 70\n")
+
+	_, err := loadConfig(configSources{file: path, flags: map[string]string{"MAX_MEMORY": "10"}})
+	if err == nil {
+		t.Fatal("Expected unknown settings to be rejected")
+	}
+	for _, want := range []string{"unknown setting TARGET_CPU_UTILISATION in " + path, "unknown setting MAX_MEMORY in --set"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %q, got %v", want, err)
+		}
+	}
+
+	if _, err := loadConfig(configSources{file: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("Expected a missing config file to be an error")
+	}
+}
+
+func TestConfigSourcesArgs(t *testing.T) {
+	sources := configSources{file: "/etc/goburn/config.yaml", flags: map[string]string{"B": "2", "A": "x=y"}}
+	expected := []string{"--config", "/etc/goburn/config.yaml", "--set", "A=x=y", "--set", "B=2"}
+	if args := sources.args(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("args() = %v, want %v", args, expected)
+	}
+
+	flags := make(setFlags)
+	if err := flags.Set("A=x=y"); err != nil || flags["A"] != "x=y" {
+		t.Errorf("Set(A=x=y) = %v, flags %v", err, flags)
+	}
+	if err := flags.Set("novalue"); err == nil {
+		t.Error("Expected a --set without = to be rejected")
+	}
+}
+
+func TestMergeReload(t *testing.T) {
+	current := validTestConfig()
+	next := current
+	next.TargetCPUUtilization = 70
+	next.ScaleUpDelay = 30 * time.Second
+	next.NetworkMode = NetworkModeTCP
+	next.MetricsSources = []string{MetricsSourceKubelet}
+
+	merged, changed, restart := mergeReload(current, next)
+	if merged.NetworkMode != NetworkModeUDP || !reflect.DeepEqual(merged.MetricsSources, current.MetricsSources) {
+		t.Errorf("Expected restart-only settings to keep their values, got %s %v", merged.NetworkMode, merged.MetricsSources)
+	}
+	if merged.TargetCPUUtilization != 70 || merged.ScaleUpDelay != 30*time.Second {
+		t.Errorf("Expected live settings to change, got %v %v", merged.TargetCPUUtilization, merged.ScaleUpDelay)
+	}
+	if !reflect.DeepEqual(changed, []string{"TargetCPUUtilization", "ScaleUpDelay"}) {
+		t.Errorf("changed = %v", changed)
+	}
+	if !reflect.DeepEqual(restart, []string{"NETWORK_MODE", "METRICS_SOURCES"}) {
+		t.Errorf("restart = %v", restart)
+	}
+}
+
+func TestResourceBurner_ApplyConfig(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.pid = newPIDControllers(rb.config)
+	rb.setMemoryMB(20)
+	defer rb.memory.Release()
+
+	// Samples survive the reload
+	now := time.Unix(0, 0)
+	rb.cpuSamples.Add(50, now)
+	rb.complianceReport(30, 25, true, now)
+
+	next := rb.config
+	next.TargetCPUUtilization = 70
+	next.MinMemoryUtilization = 25
+	next.MaxMemoryMB = 10
+	next.PercentileWindow = 24 * time.Hour
+	next.CPUGains = PIDGains{Kp: 2}
+	next.NodeName = "other-node"
+	rb.applyConfig(configReload{config: next, source: "test.yaml"}, nil)
+
+	if rb.config.TargetCPUUtilization != 70 || rb.currentConfig().TargetCPUUtilization != 70 {
+		t.Errorf("Expected the new target, got %v", rb.config.TargetCPUUtilization)
+	}
+	if rb.config.NodeName != "test-node" {
+		t.Errorf("Expected NODE_NAME to need a restart, got %q", rb.config.NodeName)
+	}
+	if rb.memory.SizeMB() != 10 {
+		t.Errorf("Expected memory to shrink to the new 10 MB cap, got %d MB", rb.memory.SizeMB())
+	}
+	if rb.cpuSamples.Window() != 24*time.Hour || rb.cpuSamples.Count() != 1 {
+		t.Errorf("Expected a 24h window keeping its sample, got %s with %d samples", rb.cpuSamples.Window(), rb.cpuSamples.Count())
+	}
+	if rb.pid.cpu.gains.Kp != 2 {
+		t.Errorf("Expected the new CPU gains, got %+v", rb.pid.cpu.gains)
+	}
+	for _, rule := range rb.compliance.rules {
+		if rule.resource == ResourceMemory && (rule.minimum != 25 || rule.window.Count() != 1) {
+			t.Errorf("Expected the memory rule at 25%% with its sample, got %.1f with %d", rule.minimum, rule.window.Count())
+		}
+	}
+}
+
+func TestResourceBurner_ApplyConfigDisablesMemory(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.pid = newPIDControllers(rb.config)
+	rb.setMemoryMB(20)
+	defer rb.memory.Release()
+	rb.complianceReport(30, 25, true, time.Unix(0, 0))
+
+	next := rb.config
+	next.EnableMemoryUtilization = false
+	rb.applyConfig(configReload{config: next, source: "node test-node"}, nil)
+
+	if rb.memory.SizeMB() != 0 {
+		t.Errorf("Expected memory to be released, got %d MB", rb.memory.SizeMB())
+	}
+	for _, rule := range rb.compliance.rules {
+		if rule.resource == ResourceMemory {
+			t.Error("Expected no memory rule once memory is disabled")
+		}
+	}
+
+	next.EnableMemoryUtilization = true
+	rb.applyConfig(configReload{config: next, source: "node test-node"}, nil)
+	found := false
+	for _, rule := range rb.compliance.rules {
+		found = found || rule.resource == ResourceMemory
+	}
+	if !found {
+		t.Error("Expected the memory rule back once memory is enabled")
+	}
+}
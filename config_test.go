@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validTestConfig returns a configuration that passes Validate
+func validTestConfig() Config {
+	return Config{
+		TargetCPUUtilization:      80,
+		TargetMemoryUtilization:   80,
+		MinCPUUtilization:         20,
+		MinMemoryUtilization:      20,
+		MinNetworkUtilizationMbps: 20,
+		MonitorInterval:           30 * time.Second,
+		ScaleUpDelay:              60 * time.Second,
+		ScaleDownDelay:            120 * time.Second,
+		MaxMemoryMB:               1024,
+		NodeName:                  "test-node",
+		EnableMemoryUtilization:   true,
+		NetworkMode:               NetworkModeUDP,
+		NetworkStreams:            2,
+		MaxNetworkMbps:            1000,
+		MetricsSources:            []string{MetricsSourceProcfs},
+		MetricsMaxAge:             2 * time.Minute,
+		Controller:                ControllerPID,
+		CPUDutyPeriod:             10 * time.Millisecond,
+		CPUPriority:               CPUPriorityNormal,
+		MemoryFill:                MemoryFillRandom,
+		PercentileWindow:          time.Hour,
+		CPUPercentile:             95,
+		MemoryPercentile:          95,
+		NetworkPercentile:         95,
+		CPUStrategy:               CPUStrategyContinuous,
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 4194304 kB\nMemAvailable: 2097152 kB\n"})
+
+	if err := validTestConfig().Validate(); err != nil {
+		t.Fatalf("Expected a valid config, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		problem string
+	}{
+		{"min above target CPU", func(c *Config) { c.MinCPUUtilization = 90 }, "MIN_CPU_UTILIZATION (90) is above TARGET_CPU_UTILIZATION (80)"},
+		{"min above target memory", func(c *Config) { c.MinMemoryUtilization = 85 }, "MIN_MEMORY_UTILIZATION (85) is above"},
+		{"negative delay", func(c *Config) { c.ScaleDownDelay = -time.Second }, "SCALE_DOWN_DELAY_SECONDS (-1s) must not be negative"},
+		{"zero interval", func(c *Config) { c.MonitorInterval = 0 }, "MONITOR_INTERVAL_SECONDS"},
+		{"more memory than the node", func(c *Config) { c.MaxMemoryMB = 8192 }, "MAX_MEMORY_MB (8192) is larger than the node's memory (4096 MB)"},
+		{"percent out of range", func(c *Config) { c.TargetCPUUtilization = 120 }, "TARGET_CPU_UTILIZATION (120) must be between 0 and 100"},
+		{"unknown controller", func(c *Config) { c.Controller = "fuzzy" }, `CONTROLLER "fuzzy" is not one of step, pid`},
+		{"unknown metrics source", func(c *Config) { c.MetricsSources = []string{"procfs", "prometheus"} }, `"prometheus"`},
+		{"window shorter than interval", func(c *Config) { c.PercentileWindow = 10 * time.Second }, "PERCENTILE_WINDOW (10s) is shorter"},
+		{"unknown checkpoint store", func(c *Config) { c.CheckpointStore = "s3"; c.CheckpointInterval = time.Minute }, `CHECKPOINT_STORE "s3"`},
+		{"negative gains", func(c *Config) { c.NetworkGains.Ki = -1 }, "PID_NETWORK_KP, _KI and _KD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validTestConfig()
+			tt.modify(&config)
+			err := config.Validate()
+			if err == nil {
+				t.Fatal("Expected a validation error")
+			}
+			if !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("Expected the error to mention %q, got %v", tt.problem, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidate_ListsEveryProblem(t *testing.T) {
+	config := validTestConfig()
+	config.MinCPUUtilization = 90
+	config.ScaleUpDelay = -time.Minute
+	config.NetworkMode = "carrier-pigeon"
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+	if problems := err.(*configError).problems; len(problems) != 3 {
+		t.Errorf("Expected 3 problems, got %d: %v", len(problems), problems)
+	}
+	if !strings.HasPrefix(err.Error(), "invalid configuration (3 problems):\n  - ") {
+		t.Errorf("Unexpected error format: %q", err.Error())
+	}
+}
+
+func TestLoadConfig_ParseErrors(t *testing.T) {
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 8388608 kB\nMemAvailable: 4194304 kB\n"})
+	for key, value := range map[string]string{
+		"NODE_NAME":              "test-node",
+		"TARGET_CPU_UTILIZATION": "eighty",
+		"SCALE_UP_DELAY_SECONDS": "1m",
+		"MIN_CPU_UTILIZATION":    "95",
+	} {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+
+	_, err := loadConfig(configSources{})
+	if err == nil {
+		t.Fatal("Expected loadConfig to fail")
+	}
+	for _, want := range []string{
+		`TARGET_CPU_UTILIZATION="eighty" is not a valid number`,
+		`SCALE_UP_DELAY_SECONDS="1m" is not a valid integer`,
+		"MIN_CPU_UTILIZATION (95) is above TARGET_CPU_UTILIZATION (80)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %q, got %v", want, err)
+		}
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 8388608 kB\nMemAvailable: 4194304 kB\n"})
+	os.Setenv("NODE_NAME", "test-node")
+	defer os.Unsetenv("NODE_NAME")
+
+	var out bytes.Buffer
+	if code := runValidate(&out, configSources{}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "Configuration is valid for node test-node") {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+
+	os.Setenv("MAX_MEMORY_MB", "lots")
+	defer os.Unsetenv("MAX_MEMORY_MB")
+	out.Reset()
+	if code := runValidate(&out, configSources{}); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(out.String(), `MAX_MEMORY_MB="lots"`) {
+		t.Errorf("Expected the bad setting in the output, got %s", out.String())
+	}
+}
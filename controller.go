@@ -0,0 +1,285 @@
+package main
+
+import (
+	"log"
+	"math"
+	"runtime"
+	"time"
+)
+
+// Supported values for CONTROLLER
+const (
+	ControllerStep = "step"
+	ControllerPID  = "pid"
+)
+
+// PIDGains are the proportional, integral and derivative gains of one
+// controller. Errors are in percentage points (Mbps for network) and time in
+// seconds, so the gains do not depend on node size.
+type PIDGains struct {
+	Kp float64
+	Ki float64
+	Kd float64
+}
+
+// pidController is a positional PID controller. Its output is goburn's own
+// share of a resource, in the same unit as the setpoint.
+type pidController struct {
+	gains  PIDGains
+	outMin float64
+	outMax float64
+
+	integral   float64
+	prevErr    float64
+	hasPrev    bool
+	output     float64
+	lastUpdate time.Time
+}
+
+func newPIDController(gains PIDGains, outMin, outMax float64) *pidController {
+	return &pidController{gains: gains, outMin: outMin, outMax: outMax}
+}
+
+// Update feeds one measurement and returns the new output
+func (c *pidController) Update(setpoint, measured float64, dt time.Duration) float64 {
+	seconds := dt.Seconds()
+	if seconds <= 0 {
+		seconds = 1
+	}
+	err := setpoint - measured
+
+	// Windup clamp: the integral term alone never exceeds the output limits,
+	// so a long saturation does not have to be unwound before the output moves
+	if c.gains.Ki > 0 {
+		c.integral += err * seconds
+		c.integral = math.Max(c.outMin/c.gains.Ki, math.Min(c.integral, c.outMax/c.gains.Ki))
+	}
+
+	derivative := 0.0
+	if c.hasPrev {
+		derivative = (err - c.prevErr) / seconds
+	}
+	c.prevErr, c.hasPrev = err, true
+
+	output := c.gains.Kp*err + c.gains.Ki*c.integral + c.gains.Kd*derivative
+	c.output = math.Max(c.outMin, math.Min(output, c.outMax))
+	return c.output
+}
+
+// elapsed returns the time since the last update at now, or fallback before
+// the first one, and marks now as the last update. Cooldowns can hold a
+// controller for several ticks, so each one keeps its own.
+func (c *pidController) elapsed(now time.Time, fallback time.Duration) time.Duration {
+	dt := fallback
+	if !c.lastUpdate.IsZero() {
+		dt = now.Sub(c.lastUpdate)
+	}
+	c.lastUpdate = now
+	return dt
+}
+
+// Reset forgets the accumulated state, e.g. after goburn released the resource
+func (c *pidController) Reset() {
+	c.integral = 0
+	c.prevErr = 0
+	c.hasPrev = false
+	c.output = 0
+	c.lastUpdate = time.Time{}
+}
+
+// Output returns the last output
+func (c *pidController) Output() float64 {
+	return c.output
+}
+
+// pidControllers holds one controller per resource
+type pidControllers struct {
+	cpu     *pidController
+	memory  *pidController
+	network *pidController
+}
+
+// newPIDControllers builds the controllers; CPU and memory output a percentage
+// of the node, network outputs Mbps
+func newPIDControllers(config Config) *pidControllers {
+	return &pidControllers{
+		cpu:     newPIDController(config.CPUGains, 0, 100),
+		memory:  newPIDController(config.MemoryGains, 0, 100),
+		network: newPIDController(config.NetworkGains, 0, config.MaxNetworkMbps),
+	}
+}
+
+// minimumSetpoint raises a setpoint for a minimum that is not met. PID
+// setpoints leave one buffer above the minimum; a rule at risk of violation
+// aims a buffer higher to lift its percentile before it falls, and a violated
+// rule two buffers higher to recover rather than hover at the minimum.
+func minimumSetpoint(setpoint, buffer float64, report []ruleStatus, resource string) float64 {
+	for _, status := range report {
+		if status.Resource != resource {
+			continue
+		}
+		switch status.State {
+		case ComplianceAtRisk:
+			return math.Max(setpoint, status.Minimum+2*buffer)
+		case ComplianceViolated:
+			return math.Max(setpoint, status.Minimum+3*buffer)
+		}
+	}
+	return setpoint
+}
+
+// runPIDControllers moves every resource toward its setpoint. The setpoints are
+// the targets, so the minimums are met along the way; network aims for the
+// minimum plus the same buffer the step controller uses. A minimum at risk or
+// violated in the compliance report raises its setpoint.
+//
+// The outputs go through the same gate as the step controller: resources are
+// handled in the order of the report, closest to violation first, and each
+// waits for its own cooldown unless its minimum is urgent or foreign
+// workloads force a release. Only a change in the load starts a cooldown.
+func (rb *ResourceBurner) runPIDControllers(report []ruleStatus, usage NodeUsage, networkMbps float64, networkKnown bool, workload WorkloadUsage, workloadKnown bool, now time.Time) {
+	for _, resource := range pidOrder(report) {
+		status := ruleStatus{Resource: resource, State: ComplianceMet}
+		for _, s := range report {
+			if s.Resource == resource {
+				status = s
+			}
+		}
+
+		switch resource {
+		case ResourceCPU:
+			// The burst planner owns CPU when it is on
+			if rb.config.CPUStrategy != CPUStrategyBurst {
+				rb.runPIDCPU(report, status, usage, workload, workloadKnown, now)
+			}
+		case ResourceMemory:
+			if rb.config.EnableMemoryUtilization && usage.MemoryCapacity > 0 {
+				rb.runPIDMemory(report, status, usage, workload, workloadKnown, now)
+			}
+		case ResourceNetwork:
+			if networkKnown {
+				rb.runPIDNetwork(report, status, networkMbps, now)
+			}
+		}
+	}
+}
+
+// pidOrder returns the resources in the order of the compliance report,
+// followed by any without a rule
+func pidOrder(report []ruleStatus) []string {
+	var order []string
+	seen := make(map[string]bool)
+	for _, status := range report {
+		if !seen[status.Resource] {
+			seen[status.Resource] = true
+			order = append(order, status.Resource)
+		}
+	}
+	for _, resource := range []string{ResourceCPU, ResourceMemory, ResourceNetwork} {
+		if !seen[resource] {
+			order = append(order, resource)
+		}
+	}
+	return order
+}
+
+// pidReady reports whether a PID controller may change a resource at now.
+// A release forced by foreign workloads skips the scale-up cooldown, and a
+// minimum that could be violated before the cooldown ends skips it entirely.
+func (rb *ResourceBurner) pidReady(status ruleStatus, release bool, now time.Time) bool {
+	cooldown := rb.cooldown(status.Resource)
+	if release {
+		return cooldown.ReadyToRelease(now, rb.config.ScaleDownDelay)
+	}
+	if rb.cooldownReady(status.Resource, now) {
+		return true
+	}
+	if status.Urgent(rb.config.ScaleUpDelay) {
+		log.Printf("⏱️  %s minimum is %s and could be violated in %s - skipping its scaling cooldown",
+			status.Resource, status.State, status.TimeToViolation.Round(time.Second))
+		return true
+	}
+	return false
+}
+
+// recordPIDChange starts the cooldown of a resource whose load changed
+func (rb *ResourceBurner) recordPIDChange(resource string, before, after float64, now time.Time) {
+	if after != before {
+		rb.cooldown(resource).Record(now, after > before)
+	}
+}
+
+// runPIDCPU: the output is the share of node CPU goburn should burn
+func (rb *ResourceBurner) runPIDCPU(report []ruleStatus, status ruleStatus, usage NodeUsage, workload WorkloadUsage, workloadKnown bool, now time.Time) {
+	pid := rb.pid.cpu
+	setpoint := minimumSetpoint(rb.config.TargetCPUUtilization, 10, report, ResourceCPU)
+	release := workloadKnown && workload.ForeignCPUPercent >= setpoint
+	if !rb.pidReady(status, release, now) {
+		return
+	}
+
+	if release {
+		pid.Reset()
+	} else {
+		pid.Update(setpoint, usage.CPUPercent, pid.elapsed(now, rb.config.MonitorInterval))
+	}
+	capacity := int64(runtime.NumCPU()) * cpuFullCore
+	if usage.CPUCapacity > 0 {
+		capacity = usage.CPUCapacity
+	}
+	before := rb.cpuMillicores()
+	millicores := rb.setCPUMillicores(int64(math.Round(pid.Output() / 100 * float64(capacity))))
+	rb.recordPIDChange(ResourceCPU, float64(before), float64(millicores), now)
+	log.Printf("🎛️  PID CPU - setpoint: %.1f%%, measured: %.1f%%, output: %.1f%% (%dm)",
+		setpoint, usage.CPUPercent, pid.Output(), millicores)
+}
+
+// runPIDMemory: the output is the share of node memory goburn should hold
+func (rb *ResourceBurner) runPIDMemory(report []ruleStatus, status ruleStatus, usage NodeUsage, workload WorkloadUsage, workloadKnown bool, now time.Time) {
+	pid := rb.pid.memory
+	setpoint := minimumSetpoint(rb.config.TargetMemoryUtilization, 10, report, ResourceMemory)
+	release := workloadKnown && workload.ForeignMemoryPercent >= setpoint
+	if !rb.pidReady(status, release, now) {
+		return
+	}
+
+	if release {
+		pid.Reset()
+	} else {
+		pid.Update(setpoint, usage.MemoryPercent, pid.elapsed(now, rb.config.MonitorInterval))
+	}
+	before := rb.memorySizeMB()
+	sizeMB := int64(pid.Output() / 100 * float64(usage.MemoryCapacity) / 1024 / 1024)
+	sizeMB = rb.setMemoryMB(sizeMB)
+	rb.recordPIDChange(ResourceMemory, float64(before), float64(sizeMB), now)
+	log.Printf("🎛️  PID Memory - setpoint: %.1f%%, measured: %.1f%%, output: %.1f%% (%d MB)",
+		setpoint, usage.MemoryPercent, pid.Output(), sizeMB)
+}
+
+// runPIDNetwork: the output is the generated rate
+func (rb *ResourceBurner) runPIDNetwork(report []ruleStatus, status ruleStatus, networkMbps float64, now time.Time) {
+	pid := rb.pid.network
+	setpoint := minimumSetpoint(rb.config.MinNetworkUtilizationMbps+5, 5, report, ResourceNetwork)
+	if !rb.pidReady(status, false, now) {
+		return
+	}
+
+	rateMbps := pid.Update(setpoint, networkMbps, pid.elapsed(now, rb.config.MonitorInterval))
+	rb.networkMutex.Lock()
+	before := rb.traffic.Rate()
+	rb.traffic.SetRate(rateMbps)
+	rb.networkMutex.Unlock()
+	rb.recordPIDChange(ResourceNetwork, before, rateMbps, now)
+	log.Printf("🎛️  PID Network - setpoint: %.1f Mbps, measured: %.1f Mbps, output: %.1f Mbps",
+		setpoint, networkMbps, rateMbps)
+}
+
+func (rb *ResourceBurner) logController() {
+	switch rb.config.Controller {
+	case ControllerPID:
+		log.Printf("🎛️  Controller: pid (CPU %+v, Memory %+v, Network %+v)",
+			rb.config.CPUGains, rb.config.MemoryGains, rb.config.NetworkGains)
+	default:
+		log.Printf("🎛️  Controller: step (±10%% dead band)")
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// simulatePlant runs the controller against a node where measured usage is
+// foreign load plus goburn's output, and returns the final measurement
+func simulatePlant(c *pidController, setpoint, foreign float64, steps int) float64 {
+	measured := foreign
+	for i := 0; i < steps; i++ {
+		output := c.Update(setpoint, measured, 30*time.Second)
+		measured = math.Min(foreign+output, 100)
+	}
+	return measured
+}
+
+func TestPIDController_Converges(t *testing.T) {
+	for _, foreign := range []float64{0, 30, 60} {
+		c := newPIDController(PIDGains{Kp: 0.5, Ki: 0.01}, 0, 100)
+		measured := simulatePlant(c, 80, foreign, 50)
+		if math.Abs(measured-80) > 1 {
+			t.Errorf("foreign %.0f%%: measured %.2f%% after 50 steps, want about 80%%", foreign, measured)
+		}
+		if math.Abs(c.Output()-(80-foreign)) > 1 {
+			t.Errorf("foreign %.0f%%: output %.2f%%, want about %.0f%%", foreign, c.Output(), 80-foreign)
+		}
+	}
+}
+
+func TestPIDController_OutputLimits(t *testing.T) {
+	c := newPIDController(PIDGains{Kp: 10, Ki: 1}, 0, 50)
+
+	if output := c.Update(80, 0, time.Second); output != 50 {
+		t.Errorf("Update() far below setpoint = %v, want the 50 upper limit", output)
+	}
+	if output := c.Update(0, 100, time.Second); output != 0 {
+		t.Errorf("Update() far above setpoint = %v, want the 0 lower limit", output)
+	}
+}
+
+func TestPIDController_WindupClamp(t *testing.T) {
+	c := newPIDController(PIDGains{Kp: 0, Ki: 0.1}, 0, 100)
+
+	// A setpoint that can never be reached saturates the output for a long time
+	for i := 0; i < 100; i++ {
+		c.Update(80, 0, 30*time.Second)
+	}
+	if c.integral*c.gains.Ki > 100 {
+		t.Fatalf("integral term = %v, want at most the output limit", c.integral*c.gains.Ki)
+	}
+
+	// Once usage overshoots, the output must start falling on the first step
+	if output := c.Update(80, 90, 30*time.Second); output >= 100 {
+		t.Errorf("Update() after overshoot = %v, want below the upper limit", output)
+	}
+}
+
+func TestPIDController_Derivative(t *testing.T) {
+	c := newPIDController(PIDGains{Kd: 10}, -1000, 1000)
+
+	if output := c.Update(50, 50, time.Second); output != 0 {
+		t.Errorf("First Update() = %v, want 0 without a previous error", output)
+	}
+	// The error grows by 10 points in 1s
+	if output := c.Update(50, 40, time.Second); output != 100 {
+		t.Errorf("Update() = %v, want 100", output)
+	}
+}
+
+func TestPIDController_Reset(t *testing.T) {
+	c := newPIDController(PIDGains{Kp: 0.5, Ki: 0.01}, 0, 100)
+	c.Update(80, 20, 30*time.Second)
+	c.Reset()
+
+	if c.Output() != 0 || c.integral != 0 || c.hasPrev {
+		t.Errorf("Reset() left state behind: output %v, integral %v", c.Output(), c.integral)
+	}
+}
+
+func TestResourceBurner_RunPIDControllers(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.Controller = ControllerPID
+	rb.config.CPUGains = PIDGains{Kp: 0.5, Ki: 0.01}
+	rb.config.MemoryGains = PIDGains{Kp: 0.5}
+	rb.config.NetworkGains = PIDGains{Kp: 1}
+	rb.config.MaxMemoryMB = 8
+	rb.pid = newPIDControllers(rb.config)
+	defer rb.releaseCPULoad()
+
+	usage := NodeUsage{
+		CPUPercent:     30,
+		MemoryPercent:  40,
+		CPUCapacity:    2000,
+		MemoryCapacity: 1024 * 1024 * 1024,
+	}
+	rb.runPIDControllers(nil, usage, 5, true, WorkloadUsage{}, false, time.Now())
+
+	// CPU: 0.5*50 + 0.01*50*30 = 40% of 2 cores is one worker at 800m
+	if rb.cpuWorkers != 1 || rb.cpuMillicores() != 800 {
+		t.Errorf("cpuWorkers = %d at %dm, want 1 at 800m", rb.cpuWorkers, rb.cpuMillicores())
+	}
+	// Memory: 0.5*40 = 20% of 1 GiB, capped at MaxMemoryMB
+	if rb.memory.SizeMB() != 8 {
+		t.Errorf("memory = %d MB, want the 8 MB cap", rb.memory.SizeMB())
+	}
+	// Network: setpoint is the 20 Mbps minimum plus 5 Mbps buffer
+	if rb.traffic.Rate() != 20 {
+		t.Errorf("traffic rate = %v, want 20", rb.traffic.Rate())
+	}
+
+	// Foreign workloads above the CPU target release goburn's CPU
+	workload := WorkloadUsage{ForeignCPUPercent: 85}
+	rb.runPIDControllers(nil, usage, 5, true, workload, true, time.Now())
+	if rb.cpuWorkers != 0 {
+		t.Errorf("cpuWorkers = %d, want 0 when foreign CPU exceeds the target", rb.cpuWorkers)
+	}
+}
+
+func TestResourceBurner_RunPIDControllersCooldown(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.Controller = ControllerPID
+	rb.config.EnableMemoryUtilization = false
+	rb.config.CPUGains = PIDGains{Kp: 0.5}
+	rb.config.NetworkGains = PIDGains{Kp: 1}
+	rb.pid = newPIDControllers(rb.config)
+	defer rb.releaseCPULoad()
+
+	now := time.Now()
+	usage := NodeUsage{CPUPercent: 30, CPUCapacity: 2000}
+	rb.runPIDControllers(nil, usage, 5, true, WorkloadUsage{}, false, now)
+	cpu, rate := rb.cpuMillicores(), rb.traffic.Rate()
+	if cpu == 0 || rate == 0 {
+		t.Fatalf("Expected the first tick to scale up, got %dm and %.1f Mbps", cpu, rate)
+	}
+
+	// Within the scale-up cooldown nothing moves, even further from the setpoints
+	usage.CPUPercent = 10
+	rb.runPIDControllers(nil, usage, 0, true, WorkloadUsage{}, false, now.Add(30*time.Second))
+	if rb.cpuMillicores() != cpu || rb.traffic.Rate() != rate {
+		t.Errorf("Expected the cooldown to hold %dm and %.1f Mbps, got %dm and %.1f Mbps",
+			cpu, rate, rb.cpuMillicores(), rb.traffic.Rate())
+	}
+
+	// An urgent minimum skips it, and is handled first
+	report := []ruleStatus{
+		{Resource: ResourceNetwork, State: ComplianceAtRisk, Minimum: 20, TimeToViolation: 10 * time.Second},
+		{Resource: ResourceCPU, State: ComplianceMet, Minimum: 20},
+	}
+	if order := pidOrder(report); order[0] != ResourceNetwork || order[1] != ResourceCPU || order[2] != ResourceMemory {
+		t.Errorf("pidOrder() = %v, want network, CPU, then memory", order)
+	}
+	rb.runPIDControllers(report, usage, 0, true, WorkloadUsage{}, false, now.Add(30*time.Second))
+	if rb.traffic.Rate() <= rate || rb.cpuMillicores() != cpu {
+		t.Errorf("Expected only the urgent network minimum to scale, got %dm and %.1f Mbps", rb.cpuMillicores(), rb.traffic.Rate())
+	}
+}
+
+func TestMinimumSetpoint(t *testing.T) {
+	report := []ruleStatus{
+		{Resource: ResourceNetwork, State: ComplianceAtRisk, Minimum: 20},
+		{Resource: ResourceCPU, State: ComplianceViolated, Minimum: 20},
+		{Resource: ResourceMemory, State: ComplianceMet, Minimum: 20},
+	}
+
+	tests := []struct {
+		resource string
+		setpoint float64
+		buffer   float64
+		want     float64
+	}{
+		{ResourceNetwork, 25, 5, 30},       // at risk: a buffer above the usual one
+		{ResourceCPU, 25, 10, 50},          // violated: two buffers above
+		{ResourceCPU, 80, 10, 80},          // a higher target already covers it
+		{ResourceMemory, 25, 10, 25},       // met
+		{ResourceNetwork + "x", 25, 5, 25}, // no rule
+	}
+	for _, tt := range tests {
+		if got := minimumSetpoint(tt.setpoint, tt.buffer, report, tt.resource); got != tt.want {
+			t.Errorf("minimumSetpoint(%v, %v, %s) = %v, want %v", tt.setpoint, tt.buffer, tt.resource, got, tt.want)
+		}
+	}
+}
+
+func TestResourceBurner_RunPIDControllersRaisesAtRiskMinimum(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.Controller = ControllerPID
+	rb.config.NetworkGains = PIDGains{Kp: 1}
+	rb.config.EnableMemoryUtilization = false
+	rb.config.CPUStrategy = CPUStrategyBurst
+	rb.pid = newPIDControllers(rb.config)
+	defer rb.traffic.Stop()
+
+	// Measured right at the 25 Mbps setpoint, so only the raise moves the rate
+	report := []ruleStatus{{Resource: ResourceNetwork, State: ComplianceAtRisk, Minimum: 20, TimeToViolation: 10 * time.Minute}}
+	rb.runPIDControllers(report, NodeUsage{}, 25, true, WorkloadUsage{}, false, time.Now())
+	if rb.traffic.Rate() != 5 {
+		t.Errorf("traffic rate = %v, want 5 toward the raised 30 Mbps setpoint", rb.traffic.Rate())
+	}
+}
+
+func TestResourceBurner_SetMemoryMB(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.MaxMemoryMB = 4
+
+	if size := rb.setMemoryMB(10); size != 4 || rb.memory.SizeMB() != 4 {
+		t.Errorf("setMemoryMB(10) = %d, %d MB held, want capped at 4 MB", size, rb.memory.SizeMB())
+	}
+	if size := rb.setMemoryMB(1); size != 1 || rb.memory.SizeMB() != 1 {
+		t.Errorf("setMemoryMB(1) = %d, %d MB held, want 1 MB", size, rb.memory.SizeMB())
+	}
+	if size := rb.setMemoryMB(-5); size != 0 || rb.memory.SizeMB() != 0 {
+		t.Errorf("setMemoryMB(-5) = %d, %d MB held, want 0", size, rb.memory.SizeMB())
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// resourceCooldown is the scaling cooldown of one resource: after a scale-up
+// the resource waits ScaleUpDelay before it scales again, after a scale-down
+// ScaleDownDelay. Each resource keeps its own, so a network scale-up does not
+// hold back a CPU scale-down.
+type resourceCooldown struct {
+	last   time.Time
+	up     bool
+	scaled bool
+}
+
+// Record notes that the resource was scaled at now
+func (c *resourceCooldown) Record(now time.Time, up bool) {
+	c.last = now
+	c.up = up
+	c.scaled = true
+}
+
+// Remaining returns how long the resource still has to wait, 0 when it may scale
+func (c *resourceCooldown) Remaining(now time.Time, upDelay, downDelay time.Duration) time.Duration {
+	if !c.scaled {
+		return 0
+	}
+	delay := downDelay
+	if c.up {
+		delay = upDelay
+	}
+	if remaining := c.last.Add(delay).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// ReadyToRelease reports whether a scale-down forced by foreign workloads may
+// go ahead: it always bypasses the scale-up cooldown, but still waits out the
+// cooldown of a previous scale-down
+func (c *resourceCooldown) ReadyToRelease(now time.Time, downDelay time.Duration) bool {
+	return c.up || c.Remaining(now, 0, downDelay) == 0
+}
+
+// foreignGrowth reports whether foreign CPU and memory usage grew since the
+// previous tick, and remembers this tick's usage for the next one
+func (rb *ResourceBurner) foreignGrowth(workload WorkloadUsage, known bool) (cpu, memory bool) {
+	prev := rb.lastWorkload
+	rb.lastWorkload = nil
+	if !known {
+		return false, false
+	}
+	rb.lastWorkload = &workload
+	if prev == nil {
+		return false, false
+	}
+	return workload.ForeignCPUPercent > prev.ForeignCPUPercent, workload.ForeignMemoryPercent > prev.ForeignMemoryPercent
+}
+
+func (c *resourceCooldown) String(now time.Time, upDelay, downDelay time.Duration) string {
+	remaining := c.Remaining(now, upDelay, downDelay)
+	if remaining == 0 {
+		return "ready"
+	}
+	direction := "down"
+	if c.up {
+		direction = "up"
+	}
+	return fmt.Sprintf("%s %s", direction, remaining.Round(time.Second))
+}
+
+// cooldown returns the cooldown state of a resource
+func (rb *ResourceBurner) cooldown(resource string) *resourceCooldown {
+	if rb.cooldowns == nil {
+		rb.cooldowns = make(map[string]*resourceCooldown)
+	}
+	c, ok := rb.cooldowns[resource]
+	if !ok {
+		c = &resourceCooldown{}
+		rb.cooldowns[resource] = c
+	}
+	return c
+}
+
+// cooldownReady reports whether a resource may scale at now
+func (rb *ResourceBurner) cooldownReady(resource string, now time.Time) bool {
+	return rb.cooldown(resource).Remaining(now, rb.config.ScaleUpDelay, rb.config.ScaleDownDelay) == 0
+}
+
+// formatCooldowns describes the cooldown of every resource for the status line
+func (rb *ResourceBurner) formatCooldowns(now time.Time) string {
+	resources := []string{ResourceCPU, ResourceMemory, ResourceNetwork}
+	parts := make([]string, len(resources))
+	for i, resource := range resources {
+		parts[i] = fmt.Sprintf("%s %s", strings.ToLower(resource),
+			rb.cooldown(resource).String(now, rb.config.ScaleUpDelay, rb.config.ScaleDownDelay))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResourceCooldown(t *testing.T) {
+	const up, down = time.Minute, 2 * time.Minute
+	now := time.Unix(0, 0)
+
+	var c resourceCooldown
+	if c.Remaining(now, up, down) != 0 || c.String(now, up, down) != "ready" {
+		t.Errorf("Expected a resource that never scaled to be ready, got %s", c.String(now, up, down))
+	}
+
+	c.Record(now, true)
+	if got := c.Remaining(now.Add(20*time.Second), up, down); got != 40*time.Second {
+		t.Errorf("Expected 40s of scale-up cooldown left, got %s", got)
+	}
+	if got := c.String(now.Add(20*time.Second), up, down); got != "up 40s" {
+		t.Errorf("Expected status \"up 40s\", got %q", got)
+	}
+	if c.Remaining(now.Add(up), up, down) != 0 {
+		t.Error("Expected the scale-up cooldown to be over after ScaleUpDelay")
+	}
+
+	// Foreign workload growth skips the scale-up cooldown but not a scale-down one
+	if !c.ReadyToRelease(now.Add(time.Second), down) {
+		t.Error("Expected a release to bypass the scale-up cooldown")
+	}
+	c.Record(now, false)
+	if c.ReadyToRelease(now.Add(time.Minute), down) {
+		t.Error("Expected a release to wait for the scale-down cooldown")
+	}
+	if got := c.Remaining(now.Add(time.Minute), up, down); got != time.Minute {
+		t.Errorf("Expected 1m of scale-down cooldown left, got %s", got)
+	}
+}
+
+func TestResourceBurner_IndependentCooldowns(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	now := time.Unix(0, 0)
+
+	// A network scale-up does not hold back CPU or memory
+	rb.cooldown(ResourceNetwork).Record(now, true)
+	if rb.cooldownReady(ResourceNetwork, now.Add(time.Second)) {
+		t.Error("Expected the network to be cooling down")
+	}
+	if !rb.cooldownReady(ResourceCPU, now.Add(time.Second)) || !rb.cooldownReady(ResourceMemory, now.Add(time.Second)) {
+		t.Error("Expected CPU and memory to be ready")
+	}
+
+	rb.cooldown(ResourceCPU).Record(now, false)
+	expected := "cpu down 1m59s, memory ready, network up 59s"
+	if got := rb.formatCooldowns(now.Add(time.Second)); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestResourceBurner_ForeignGrowth(t *testing.T) {
+	rb := createTestResourceBurner(t)
+
+	if cpu, memory := rb.foreignGrowth(WorkloadUsage{ForeignCPUPercent: 40, ForeignMemoryPercent: 40}, true); cpu || memory {
+		t.Error("Expected no growth without a previous tick")
+	}
+	if cpu, memory := rb.foreignGrowth(WorkloadUsage{ForeignCPUPercent: 50, ForeignMemoryPercent: 30}, true); !cpu || memory {
+		t.Errorf("foreignGrowth() = %v, %v, want CPU growth only", cpu, memory)
+	}
+
+	// An unknown split forgets the previous tick
+	rb.foreignGrowth(WorkloadUsage{}, false)
+	if cpu, _ := rb.foreignGrowth(WorkloadUsage{ForeignCPUPercent: 90}, true); cpu {
+		t.Error("Expected no growth after a tick without the workload split")
+	}
+}
+
+func TestResourceBurner_ScaleCPUToTarget(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.TargetCPUUtilization = 80
+	rb.config.ScaleUpDelay = 10 * time.Minute
+	rb.config.ScaleDownDelay = time.Minute
+	defer rb.releaseCPULoad()
+	now := time.Now()
+
+	// A scale-up held by CPU pressure changes nothing and starts no cooldown
+	rb.cpuPressured = true
+	rb.scaleCPUToTarget(30, WorkloadUsage{}, false, false, now)
+	if rb.cpuWorkers != 0 || !rb.cooldownReady(ResourceCPU, now) {
+		t.Errorf("Expected a held scale-up to leave %d workers and no cooldown", rb.cpuWorkers)
+	}
+	rb.cpuPressured = false
+
+	rb.scaleCPUToTarget(30, WorkloadUsage{}, false, false, now)
+	workers := rb.cpuWorkers
+	if workers == 0 || rb.cooldownReady(ResourceCPU, now) {
+		t.Fatalf("Expected a scale-up with a cooldown, got %d workers", workers)
+	}
+
+	// Over target without foreign growth waits for the scale-up cooldown
+	later := now.Add(time.Minute)
+	rb.scaleCPUToTarget(95, WorkloadUsage{ForeignCPUPercent: 50}, true, false, later)
+	if rb.cpuWorkers != workers {
+		t.Errorf("Expected the scale-up cooldown to hold %d workers, got %d", workers, rb.cpuWorkers)
+	}
+
+	// Foreign growth below the target still skips it
+	rb.scaleCPUToTarget(95, WorkloadUsage{ForeignCPUPercent: 60}, true, true, later)
+	if rb.cpuWorkers >= workers {
+		t.Errorf("Expected foreign growth to scale down from %d workers, got %d", workers, rb.cpuWorkers)
+	}
+}
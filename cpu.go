@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+)
+
+// Supported values for CPU_PRIORITY
+const (
+	CPUPriorityNormal = "normal"
+	CPUPriorityIdle   = "idle"
+	CPUPriorityNice   = "nice"
+)
+
+// cpuFullCore is one core in millicores
+const cpuFullCore = 1000
+
+// defaultCPUDutyPeriod is used when CPU_DUTY_PERIOD_MS is not set
+const defaultCPUDutyPeriod = 10 * time.Millisecond
+
+// cpuPressureInterval is how often tenant CPU pressure is checked, so stalled
+// tenants get the CPU back within a second instead of the next tick
+const cpuPressureInterval = time.Second
+
+// controlProcs is GOMAXPROCS at startup: the Ps left to the control loop,
+// the guards and the runtime once low-priority workers have theirs
+var controlProcs = runtime.GOMAXPROCS(0)
+
+// burnCPU does one small unit of CPU intensive work
+func burnCPU() {
+	key := rnd(32)
+	decrypt(key, encrypt(key, key))
+}
+
+// setCPUMillicores spreads a total CPU budget over as few workers as possible,
+// each running the same duty cycle, e.g. 2200m is three workers at 733m. The
+// budget is capped at every core of the node; the applied value is returned.
+func (rb *ResourceBurner) setCPUMillicores(millicores int64) int64 {
+	rb.cpuMutex.Lock()
+	defer rb.cpuMutex.Unlock()
+
+	millicores = max(0, min(millicores, int64(runtime.NumCPU())*cpuFullCore))
+	if rb.cpuStarved || rb.cpuPressured {
+		millicores = min(millicores, rb.cpuMillicoresLocked())
+	}
+	workers := int((millicores + cpuFullCore - 1) / cpuFullCore)
+	if workers > 0 {
+		rb.workerMillicores.Store(millicores / int64(workers))
+	}
+	rb.setCPUWorkersLocked(workers)
+	return rb.cpuMillicoresLocked()
+}
+
+// cpuMillicores returns the CPU budget currently being burned
+func (rb *ResourceBurner) cpuMillicores() int64 {
+	rb.cpuMutex.RLock()
+	defer rb.cpuMutex.RUnlock()
+	return rb.cpuMillicoresLocked()
+}
+
+func (rb *ResourceBurner) cpuMillicoresLocked() int64 {
+	return int64(rb.cpuWorkers) * rb.workerDuty()
+}
+
+// workerDuty returns the per-worker duty cycle in millicores; workers started
+// without one (the step controller) run a full core
+func (rb *ResourceBurner) workerDuty() int64 {
+	duty := rb.workerMillicores.Load()
+	if duty <= 0 || duty > cpuFullCore {
+		return cpuFullCore
+	}
+	return duty
+}
+
+func (rb *ResourceBurner) cpuDutyPeriod() time.Duration {
+	period := rb.currentConfig().CPUDutyPeriod
+	if period <= 0 {
+		return defaultCPUDutyPeriod
+	}
+	return period
+}
+
+// cpuWorkerSettings are taken from the config when a worker starts, so running
+// workers never read a config that a reload may be replacing
+type cpuWorkerSettings struct {
+	period   time.Duration
+	priority string
+	nice     int
+}
+
+func (rb *ResourceBurner) cpuWorkerSettings() cpuWorkerSettings {
+	config := rb.currentConfig()
+	return cpuWorkerSettings{period: rb.cpuDutyPeriod(), priority: config.CPUPriority, nice: config.CPUNice}
+}
+
+func (s cpuWorkerSettings) lowered() bool {
+	return s.priority != "" && s.priority != CPUPriorityNormal
+}
+
+// reserveControlProcs raises GOMAXPROCS so that low-priority workers
+// never hold every P. The kernel starves such a thread on a busy node while it
+// keeps its P, and without a spare P the monitor, the memory guard and the
+// garbage collector's stop-the-world would wait for it, exactly when tenants
+// need the CPU back. GOMAXPROCS is only ever raised.
+func reserveControlProcs(workers int) {
+	if want := controlProcs + workers; runtime.GOMAXPROCS(0) < want {
+		runtime.GOMAXPROCS(want)
+	}
+}
+
+// lowerWorkerPriority pins the calling worker to its own OS thread and drops
+// that thread to SCHED_IDLE or a high nice value, so the kernel preempts the
+// burn as soon as a real workload wants the core. The thread is never unlocked:
+// when the worker exits the runtime discards it instead of reusing a
+// low-priority thread for other goroutines. Callers reserve a P for it first
+// with reserveControlProcs.
+func (rb *ResourceBurner) lowerWorkerPriority(settings cpuWorkerSettings) {
+	if !settings.lowered() {
+		return
+	}
+
+	runtime.LockOSThread()
+	if err := lowerThreadPriority(settings.priority, settings.nice); err != nil {
+		rb.priorityWarning.Do(func() {
+			log.Printf("⚠️  Cannot lower CPU worker priority, workers run at normal priority: %v", err)
+		})
+	}
+}
+
+// readCPUPressure returns the CPU pressure of the tenant pods. Outside of
+// Kubernetes it falls back to the whole host, which includes goburn's own
+// burn threads.
+func (rb *ResourceBurner) readCPUPressure() (pressure, error) {
+	p, err := readTenantCPUPressure()
+	if err == nil {
+		return p, nil
+	}
+	rb.cpuPressureWarning.Do(func() {
+		log.Printf("⚠️  Cannot read tenant CPU pressure, using /proc/pressure/cpu, which includes goburn's own burn: %v", err)
+	})
+	return readPressure("cpu")
+}
+
+// cpuPressureWorker checks tenant CPU pressure between monitor ticks
+func (rb *ResourceBurner) cpuPressureWorker(ctx context.Context) {
+	ticker := time.NewTicker(cpuPressureInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if p, err := rb.readCPUPressure(); err == nil {
+				rb.applyCPUPressure(p)
+			}
+		}
+	}
+}
+
+// isCPUPressured reports whether CPU scale-up is on hold for CPU pressure
+func (rb *ResourceBurner) isCPUPressured() bool {
+	rb.cpuMutex.RLock()
+	defer rb.cpuMutex.RUnlock()
+	return rb.cpuPressured
+}
+
+// applyCPUPressure backs CPU workers off when tenants stall on CPU, whatever
+// the utilization says. At or above CPU_PRESSURE_THRESHOLD (PSI "some avg10")
+// it releases CPU_PRESSURE_RELEASE_MILLICORES, or everything when that is 0,
+// and holds scale-up until the pressure drops. It reports whether it backed off.
+func (rb *ResourceBurner) applyCPUPressure(p pressure) bool {
+	config := rb.currentConfig()
+	pressured := config.CPUPressureThreshold > 0 && p.Some.Avg10 >= config.CPUPressureThreshold
+
+	rb.cpuMutex.Lock()
+	rb.cpuPressured = pressured
+	current := rb.cpuMillicoresLocked()
+	rb.cpuMutex.Unlock()
+
+	if !pressured || current == 0 {
+		return false
+	}
+
+	target := int64(0)
+	if step := config.CPUPressureReleaseMillicores; step > 0 {
+		target = max(0, current-step)
+	}
+	applied := rb.setCPUMillicores(target)
+	log.Printf("🔻 Tenant CPU pressure %.1f%% at or above %.1f%% - backing CPU burn off from %dm to %dm",
+		p.Some.Avg10, config.CPUPressureThreshold, current, applied)
+	return true
+}
+
+// formatPressure renders PSI "some avg10" for the status line
+func formatPressure(p pressure, known bool) string {
+	if !known {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f%%", p.Some.Avg10)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestResourceBurner_SetCPUMillicores(t *testing.T) {
+	if runtime.NumCPU() < 3 {
+		t.Skip("needs at least 3 CPUs")
+	}
+	rb := createTestResourceBurner(t)
+	defer rb.releaseCPULoad()
+
+	tests := []struct {
+		millicores int64
+		workers    int
+		duty       int64
+	}{
+		{220, 1, 220},
+		{1000, 1, 1000},
+		{2200, 3, 733},
+		{0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		applied := rb.setCPUMillicores(tt.millicores)
+		if rb.cpuWorkers != tt.workers {
+			t.Errorf("setCPUMillicores(%d): %d workers, want %d", tt.millicores, rb.cpuWorkers, tt.workers)
+		}
+		if tt.workers > 0 && rb.workerDuty() != tt.duty {
+			t.Errorf("setCPUMillicores(%d): duty %dm, want %dm", tt.millicores, rb.workerDuty(), tt.duty)
+		}
+		if applied != int64(tt.workers)*tt.duty {
+			t.Errorf("setCPUMillicores(%d) = %d, want %d", tt.millicores, applied, int64(tt.workers)*tt.duty)
+		}
+	}
+}
+
+func TestResourceBurner_SetCPUMillicores_Capped(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	defer rb.releaseCPULoad()
+
+	maxMillicores := int64(runtime.NumCPU()) * cpuFullCore
+	if applied := rb.setCPUMillicores(maxMillicores * 4); applied != maxMillicores {
+		t.Errorf("setCPUMillicores() = %d, want capped at %d", applied, maxMillicores)
+	}
+	if applied := rb.setCPUMillicores(-100); applied != 0 || rb.cpuWorkers != 0 {
+		t.Errorf("setCPUMillicores(-100) = %d with %d workers, want 0", applied, rb.cpuWorkers)
+	}
+}
+
+func TestResourceBurner_WorkerDuty(t *testing.T) {
+	rb := createTestResourceBurner(t)
+
+	// Workers started by the step controller never had a duty set
+	if rb.workerDuty() != cpuFullCore {
+		t.Errorf("workerDuty() = %d, want a full core by default", rb.workerDuty())
+	}
+
+	rb.workerMillicores.Store(370)
+	if rb.workerDuty() != 370 {
+		t.Errorf("workerDuty() = %d, want 370", rb.workerDuty())
+	}
+
+	if rb.cpuDutyPeriod() != defaultCPUDutyPeriod {
+		t.Errorf("cpuDutyPeriod() = %v, want %v", rb.cpuDutyPeriod(), defaultCPUDutyPeriod)
+	}
+	rb.config.CPUDutyPeriod = 50 * time.Millisecond
+	if rb.cpuDutyPeriod() != 50*time.Millisecond {
+		t.Errorf("cpuDutyPeriod() = %v, want 50ms", rb.cpuDutyPeriod())
+	}
+}
+
+func TestResourceBurner_CPUWorkerStopsWhileIdle(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.CPUDutyPeriod = time.Second
+	rb.workerMillicores.Store(1)
+
+	stopChan := make(chan bool, 1)
+	done := make(chan struct{})
+	go func() {
+		rb.cpuWorker(stopChan, rb.cpuWorkerSettings())
+		close(done)
+	}()
+
+	// The worker spends almost the whole period asleep and must still stop promptly
+	time.Sleep(50 * time.Millisecond)
+	stopChan <- true
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("Worker did not stop during its idle phase")
+	}
+}
+
+func TestResourceBurner_IdleWorkersLeaveControlProcs(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	rb := createTestResourceBurner(t)
+	rb.config.CPUPriority = CPUPriorityIdle
+	defer rb.releaseCPULoad()
+
+	rb.cpuMutex.Lock()
+	rb.setCPUWorkersLocked(3)
+	rb.cpuMutex.Unlock()
+	if procs := runtime.GOMAXPROCS(0); procs < controlProcs+3 {
+		t.Errorf("GOMAXPROCS = %d with 3 idle workers, want at least %d", procs, controlProcs+3)
+	}
+}
+
+func TestResourceBurner_AdjustCPULoadUsesFullCores(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	defer rb.releaseCPULoad()
+
+	rb.workerMillicores.Store(250)
+	rb.adjustCPULoad(90.0, 10.0)
+	if rb.workerDuty() != cpuFullCore {
+		t.Errorf("workerDuty() = %d, want step workers to run full cores", rb.workerDuty())
+	}
+	if rb.cpuMillicores() != int64(rb.cpuWorkers)*cpuFullCore {
+		t.Errorf("cpuMillicores() = %d, want %d", rb.cpuMillicores(), rb.cpuWorkers*cpuFullCore)
+	}
+}
+
+func TestResourceBurner_ApplyCPUPressure(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.CPUPressureThreshold = 10
+	rb.config.CPUPressureReleaseMillicores = 300
+	defer rb.releaseCPULoad()
+
+	rb.setCPUMillicores(900)
+
+	// Low pressure leaves the burn alone
+	if rb.applyCPUPressure(pressure{Some: pressureStats{Avg10: 2}}) {
+		t.Error("applyCPUPressure() backed off below the threshold")
+	}
+	if rb.cpuMillicores() != 900 {
+		t.Errorf("cpuMillicores() = %d, want 900", rb.cpuMillicores())
+	}
+
+	// Stalls back off by one step even though utilization was not checked at all
+	if !rb.applyCPUPressure(pressure{Some: pressureStats{Avg10: 25}}) {
+		t.Error("applyCPUPressure() did not back off above the threshold")
+	}
+	if rb.cpuMillicores() != 600 {
+		t.Errorf("cpuMillicores() = %d, want 600 after one release step", rb.cpuMillicores())
+	}
+
+	// Scale-up is held while the pressure lasts
+	if applied := rb.setCPUMillicores(1000); applied != 600 {
+		t.Errorf("setCPUMillicores(1000) under pressure = %d, want held at 600", applied)
+	}
+	rb.adjustCPULoad(90.0, 10.0)
+	if rb.cpuMillicores() != 600 {
+		t.Errorf("adjustCPULoad() under pressure changed the burn to %dm", rb.cpuMillicores())
+	}
+
+	rb.applyCPUPressure(pressure{Some: pressureStats{Avg10: 1}})
+	if applied := rb.setCPUMillicores(1000); applied != 1000 {
+		t.Errorf("setCPUMillicores(1000) after the pressure = %d, want 1000", applied)
+	}
+}
+
+func TestResourceBurner_ApplyCPUPressureReleasesAll(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.CPUPressureThreshold = 10
+	rb.config.CPUPressureReleaseMillicores = 0
+	defer rb.releaseCPULoad()
+
+	rb.setCPUMillicores(800)
+	rb.applyCPUPressure(pressure{Some: pressureStats{Avg10: 50}})
+	if rb.cpuWorkers != 0 {
+		t.Errorf("cpuWorkers = %d, want every worker stopped", rb.cpuWorkers)
+	}
+
+	// A zero threshold turns the backoff off
+	rb.config.CPUPressureThreshold = 0
+	rb.setCPUMillicores(800)
+	if rb.applyCPUPressure(pressure{Some: pressureStats{Avg10: 90}}) {
+		t.Error("applyCPUPressure() backed off with the threshold disabled")
+	}
+}
+
+func TestFormatPressure(t *testing.T) {
+	if got := formatPressure(pressure{Some: pressureStats{Avg10: 12.34}}, true); got != "12.3%" {
+		t.Errorf("formatPressure() = %q, want 12.3%%", got)
+	}
+	if got := formatPressure(pressure{}, false); got != "n/a" {
+		t.Errorf("formatPressure(unknown) = %q, want n/a", got)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// emergencyOwnHistory is how long goburn's own usage is remembered, enough to
+// cover the window of a lagging metrics-server reading
+const emergencyOwnHistory = 5 * time.Minute
+
+// emergencyState remembers the foreign workload usage of the last tick, to
+// spot sudden jumps, goburn's own usage of recent ticks, and until when
+// goburn stays quiet after an emergency
+type emergencyState struct {
+	lastCPU     float64
+	lastMemory  float64
+	known       bool
+	own         []ownSample
+	quietUntil  time.Time
+	quietLogged bool
+}
+
+// ownSample is goburn's own share of the node at one tick
+type ownSample struct {
+	at     time.Time
+	cpu    float64
+	memory float64
+}
+
+// releasedSince returns how much more goburn used at most during the interval
+// node usage was measured over than it uses now. Node usage from
+// metrics-server covers a window up to a minute old while own usage comes
+// from the live cgroup, so burn released since then still shows up in node
+// usage and would count as a foreign jump of the same size.
+func (s *emergencyState) releasedSince(usage NodeUsage, workload WorkloadUsage, now time.Time) (cpu, memory float64) {
+	kept := s.own[:0]
+	for _, sample := range s.own {
+		if now.Sub(sample.at) < emergencyOwnHistory {
+			kept = append(kept, sample)
+		}
+	}
+	s.own = append(kept, ownSample{at: now, cpu: workload.OwnCPUPercent, memory: workload.OwnMemoryPercent})
+
+	if usage.Timestamp.IsZero() {
+		return 0, 0
+	}
+	start := usage.Timestamp.Add(-usage.Window)
+	for _, sample := range s.own {
+		if sample.at.Before(start) {
+			continue
+		}
+		cpu = math.Max(cpu, sample.cpu-workload.OwnCPUPercent)
+		memory = math.Max(memory, sample.memory-workload.OwnMemoryPercent)
+	}
+	return cpu, memory
+}
+
+// emergencyReason returns why foreign workloads need the node right now, or ""
+// when they do not: usage minus goburn's own burn jumped by EMERGENCY_JUMP_PERCENT
+// since the last tick, or reached EMERGENCY_CEILING_PERCENT
+func emergencyReason(cpu, memory, lastCPU, lastMemory float64, lastKnown bool, config Config) string {
+	if ceiling := config.EmergencyCeilingPercent; ceiling > 0 {
+		if cpu >= ceiling {
+			return fmt.Sprintf("foreign CPU %.1f%% reached the %.1f%% ceiling", cpu, ceiling)
+		}
+		if memory >= ceiling {
+			return fmt.Sprintf("foreign memory %.1f%% reached the %.1f%% ceiling", memory, ceiling)
+		}
+	}
+	if jump := config.EmergencyJumpPercent; jump > 0 && lastKnown {
+		if cpu-lastCPU >= jump {
+			return fmt.Sprintf("foreign CPU jumped from %.1f%% to %.1f%%", lastCPU, cpu)
+		}
+		if memory-lastMemory >= jump {
+			return fmt.Sprintf("foreign memory jumped from %.1f%% to %.1f%%", lastMemory, memory)
+		}
+	}
+	return ""
+}
+
+// checkEmergency backs off everything at once when foreign workloads suddenly
+// need the node, and reports whether goburn is holding still: during the
+// emergency tick and the EMERGENCY_QUIET_SECONDS after it, no resource ramps
+// back up and the scale delays do not apply.
+func (rb *ResourceBurner) checkEmergency(usage NodeUsage, workload WorkloadUsage, workloadKnown bool, now time.Time) bool {
+	state := &rb.emergency
+	if workloadKnown {
+		releasedCPU, releasedMemory := state.releasedSince(usage, workload, now)
+		cpu := workload.ForeignCPUPercent - releasedCPU
+		memory := workload.ForeignMemoryPercent - releasedMemory
+		reason := emergencyReason(cpu, memory, state.lastCPU, state.lastMemory, state.known, rb.config)
+		state.lastCPU, state.lastMemory, state.known = cpu, memory, true
+		if reason != "" {
+			rb.emergencyBackoff(reason, now)
+			return true
+		}
+	}
+
+	if now.Before(state.quietUntil) {
+		log.Printf("🤫 Quiet after emergency backoff - ramping back in %s", state.quietUntil.Sub(now).Round(time.Second))
+		return true
+	}
+	if !state.quietLogged && !state.quietUntil.IsZero() {
+		state.quietLogged = true
+		log.Printf("✅ Quiet period over - ramping back")
+	}
+	return false
+}
+
+// emergencyBackoff stops every CPU worker and all traffic, releases the whole
+// memory balloon and starts the quiet period
+func (rb *ResourceBurner) emergencyBackoff(reason string, now time.Time) {
+	log.Printf("🚨 Emergency backoff: %s - stopping CPU and network workers and releasing %d MB of memory, quiet for %s",
+		reason, rb.memorySizeMB(), rb.config.EmergencyQuietPeriod)
+
+	rb.releaseCPULoad()
+	rb.releaseNetworkLoad()
+	rb.releaseMemoryLoad()
+	if rb.pid != nil {
+		rb.pid.cpu.Reset()
+		rb.pid.memory.Reset()
+		rb.pid.network.Reset()
+	}
+	for _, resource := range []string{ResourceCPU, ResourceMemory, ResourceNetwork} {
+		rb.cooldown(resource).Record(now, false)
+	}
+
+	rb.emergency.quietUntil = now.Add(rb.config.EmergencyQuietPeriod)
+	rb.emergency.quietLogged = false
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmergencyReason(t *testing.T) {
+	config := Config{EmergencyJumpPercent: 30, EmergencyCeilingPercent: 95}
+
+	tests := []struct {
+		name                string
+		cpu, memory         float64
+		lastCPU, lastMemory float64
+		lastKnown           bool
+		want                string
+	}{
+		{"steady", 40, 40, 35, 38, true, ""},
+		{"CPU jump", 70, 40, 35, 40, true, "foreign CPU jumped"},
+		{"memory jump", 40, 75, 40, 40, true, "foreign memory jumped"},
+		{"no previous tick", 70, 40, 0, 0, false, ""},
+		{"CPU ceiling", 96, 40, 90, 40, true, "foreign CPU 96.0% reached"},
+		{"memory ceiling without history", 10, 95, 0, 0, false, "foreign memory 95.0% reached"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := emergencyReason(tt.cpu, tt.memory, tt.lastCPU, tt.lastMemory, tt.lastKnown, config)
+			if tt.want == "" && got != "" {
+				t.Errorf("Expected no emergency, got %q", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("Expected a reason containing %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	// Zero disables both triggers
+	if got := emergencyReason(100, 100, 0, 0, true, Config{}); got != "" {
+		t.Errorf("Expected disabled triggers not to fire, got %q", got)
+	}
+}
+
+func TestResourceBurner_EmergencyBackoff(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.EmergencyJumpPercent = 30
+	rb.config.EmergencyCeilingPercent = 95
+	rb.config.EmergencyQuietPeriod = 5 * time.Minute
+	defer rb.traffic.Stop()
+
+	rb.cpuMutex.Lock()
+	rb.setCPUWorkersLocked(1)
+	rb.cpuMutex.Unlock()
+	rb.setMemoryMB(8)
+	rb.traffic.SetRate(10)
+
+	now := time.Unix(0, 0)
+	if rb.checkEmergency(NodeUsage{}, WorkloadUsage{ForeignCPUPercent: 10, ForeignMemoryPercent: 20}, true, now) {
+		t.Fatal("Expected no emergency on the first tick")
+	}
+	if rb.cpuWorkers != 1 || rb.memory.SizeMB() != 8 || rb.traffic.Rate() != 10 {
+		t.Fatal("Expected the burn to be left alone")
+	}
+
+	// Foreign CPU jumps by 40 points in one tick
+	now = now.Add(30 * time.Second)
+	if !rb.checkEmergency(NodeUsage{}, WorkloadUsage{ForeignCPUPercent: 50, ForeignMemoryPercent: 20}, true, now) {
+		t.Fatal("Expected an emergency backoff")
+	}
+	if rb.cpuWorkers != 0 || rb.memory.SizeMB() != 0 || rb.traffic.Rate() != 0 {
+		t.Errorf("Expected everything released, got %d workers, %d MB, %.1f Mbps",
+			rb.cpuWorkers, rb.memory.SizeMB(), rb.traffic.Rate())
+	}
+
+	// Held through the quiet period even without a new trigger or workload data
+	if !rb.checkEmergency(NodeUsage{}, WorkloadUsage{ForeignCPUPercent: 50, ForeignMemoryPercent: 20}, true, now.Add(time.Minute)) {
+		t.Error("Expected the quiet period to hold")
+	}
+	if !rb.checkEmergency(NodeUsage{}, WorkloadUsage{}, false, now.Add(4*time.Minute)) {
+		t.Error("Expected the quiet period to hold without workload data")
+	}
+	if rb.checkEmergency(NodeUsage{}, WorkloadUsage{ForeignCPUPercent: 50, ForeignMemoryPercent: 20}, true, now.Add(5*time.Minute)) {
+		t.Error("Expected to ramp back after the quiet period")
+	}
+}
+
+func TestResourceBurner_EmergencyIgnoresOwnRelease(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.EmergencyJumpPercent = 30
+	rb.config.EmergencyCeilingPercent = 95
+	rb.config.EmergencyQuietPeriod = 5 * time.Minute
+	defer rb.traffic.Stop()
+
+	now := time.Unix(600, 0)
+	usage := NodeUsage{Timestamp: now.Add(-10 * time.Second), Window: time.Minute}
+	if rb.checkEmergency(usage, WorkloadUsage{OwnCPUPercent: 60, ForeignCPUPercent: 10}, true, now) {
+		t.Fatal("Expected no emergency on the first tick")
+	}
+
+	// goburn released 60 points, but the lagging node reading still holds them
+	now = now.Add(30 * time.Second)
+	usage = NodeUsage{Timestamp: now.Add(-20 * time.Second), Window: time.Minute}
+	if rb.checkEmergency(usage, WorkloadUsage{OwnCPUPercent: 0, ForeignCPUPercent: 70}, true, now) {
+		t.Error("Expected goburn's own release not to count as a foreign jump")
+	}
+
+	// Once the window has moved past the release, a real jump still triggers
+	now = now.Add(2 * time.Minute)
+	usage = NodeUsage{Timestamp: now.Add(-10 * time.Second), Window: time.Minute}
+	if !rb.checkEmergency(usage, WorkloadUsage{OwnCPUPercent: 0, ForeignCPUPercent: 50}, true, now) {
+		t.Error("Expected a foreign jump of 40 points to trigger")
+	}
+}
@@ -28,11 +28,12 @@ func TestResourceBurner_GetNodeMetrics_NotFound(t *testing.T) {
 	rb := &ResourceBurner{
 		config:        config,
 		metricsClient: metricsClient,
+		metricsSource: newMetricsServerSource(nil, metricsClient, config.NodeName),
 	}
 
 	// Test that we get an error for nonexistent node
 	ctx := context.Background()
-	_, err := rb.getNodeMetrics(ctx)
+	_, err := rb.metricsSource.NodeUsage(ctx)
 	if err == nil {
 		t.Error("Expected error for nonexistent node, got nil")
 	}
@@ -49,7 +50,7 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 		currentNetwork         float64
 		expectedCPUWorkers     int
 		expectedMemoryIncrease bool
-		expectedNetworkWorkers int
+		expectedNetworkTraffic bool
 		cpu95thPercentile      float64
 	}{
 		{
@@ -60,7 +61,7 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 			cpu95thPercentile:      15.0,
 			expectedCPUWorkers:     1, // Should scale up
 			expectedMemoryIncrease: true,
-			expectedNetworkWorkers: 1,
+			expectedNetworkTraffic: true,
 		},
 		{
 			name:                   "CPU below minimum only",
@@ -70,7 +71,7 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 			cpu95thPercentile:      15.0,
 			expectedCPUWorkers:     1,
 			expectedMemoryIncrease: false,
-			expectedNetworkWorkers: 0,
+			expectedNetworkTraffic: false,
 		},
 		{
 			name:                   "all above minimum",
@@ -80,7 +81,7 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 			cpu95thPercentile:      25.0,
 			expectedCPUWorkers:     0,
 			expectedMemoryIncrease: false,
-			expectedNetworkWorkers: 0,
+			expectedNetworkTraffic: false,
 		},
 	}
 
@@ -88,11 +89,9 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Reset ResourceBurner state
 			rb.cpuWorkers = 0
-			rb.networkWorkers = 0
-			rb.memoryData = make([]byte, 0)
+			rb.traffic.Stop()
 			rb.stopChannels = make([]chan bool, 0)
-			rb.networkStopChans = make([]chan bool, 0)
-			rb.cpuSamples = make([]float64, 0)
+			rb.cpuSamples.Reset()
 
 			// Add CPU samples to establish percentile
 			for i := 0; i < 10; i++ {
@@ -105,9 +104,9 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 			}
 
 			if rb.config.EnableMemoryUtilization && tt.currentMemory < rb.config.MinMemoryUtilization {
-				initialMemory := len(rb.memoryData)
+				initialMemory := rb.memory.SizeMB()
 				rb.adjustMemoryLoad(rb.config.MinMemoryUtilization+10, tt.currentMemory)
-				if tt.expectedMemoryIncrease && len(rb.memoryData) <= initialMemory {
+				if tt.expectedMemoryIncrease && rb.memory.SizeMB() <= initialMemory {
 					t.Errorf("Expected memory to increase, but it didn't")
 				}
 			}
@@ -122,8 +121,8 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 				t.Logf("CPU workers remained at %d (this may be expected behavior)", rb.cpuWorkers)
 			}
 
-			if tt.expectedNetworkWorkers > 0 && rb.networkWorkers == 0 {
-				t.Errorf("Expected network workers to be scaled up, got %d", rb.networkWorkers)
+			if tt.expectedNetworkTraffic && rb.traffic.Rate() == 0 {
+				t.Errorf("Expected network traffic to be scaled up, got %.1f Mbps", rb.traffic.Rate())
 			}
 		})
 	}
@@ -132,22 +131,26 @@ func TestResourceBurner_ScalingBehavior(t *testing.T) {
 func TestResourceBurner_CPUSampleManagement(t *testing.T) {
 	rb := createTestResourceBurner(t)
 
-	// Test that samples are limited to 100
+	rb.cpuSamples = newPercentileWindow(time.Hour, 95, 30*time.Second)
+
+	// Test that samples are limited to the window: 150 samples 30s apart span 75 minutes
+	start := time.Unix(0, 0)
 	for i := 0; i < 150; i++ {
-		rb.addCPUSample(float64(i))
+		rb.cpuSamples.Add(float64(i%100), start.Add(time.Duration(i)*30*time.Second))
 	}
 
-	if len(rb.cpuSamples) != 100 {
-		t.Errorf("Expected 100 samples, got %d", len(rb.cpuSamples))
+	// The newest sample is in minute 74, so minutes 14 to 74 are kept
+	if rb.cpuSamples.Count() != 122 {
+		t.Errorf("Expected 122 samples, got %d", rb.cpuSamples.Count())
 	}
 
-	// Verify that the oldest samples were removed
-	if rb.cpuSamples[0] != 50.0 { // Should start from 50 (150-100)
-		t.Errorf("Expected first sample to be 50.0, got %f", rb.cpuSamples[0])
+	// Verify that the oldest samples were removed: 28..99 and 0..49 remain
+	if rb.cpuSamples.buckets[0].minute != 14 {
+		t.Errorf("Expected the oldest bucket to be minute 14, got %d", rb.cpuSamples.buckets[0].minute)
 	}
-
-	if rb.cpuSamples[99] != 149.0 { // Should end at 149
-		t.Errorf("Expected last sample to be 149.0, got %f", rb.cpuSamples[99])
+	// Rank 116 of 122: 0-27 once, 28-49 twice, then 50 onwards
+	if percentile := rb.getCPUPercentile(); percentile != 93.0 {
+		t.Errorf("Expected 95th percentile to be 93, got %f", percentile)
 	}
 }
 
@@ -160,10 +163,9 @@ func TestResourceBurner_MemoryAllocationLimits(t *testing.T) {
 	// Try to allocate more than the limit
 	rb.adjustMemoryLoad(90.0, 10.0) // Large difference should trigger allocation
 
-	maxExpectedBytes := rb.config.MaxMemoryMB * 1024 * 1024
-	if int64(len(rb.memoryData)) > maxExpectedBytes {
-		t.Errorf("Memory allocation exceeded limit: got %d bytes, max allowed %d bytes",
-			len(rb.memoryData), maxExpectedBytes)
+	if rb.memory.SizeMB() > rb.config.MaxMemoryMB {
+		t.Errorf("Memory allocation exceeded limit: got %d MB, max allowed %d MB",
+			rb.memory.SizeMB(), rb.config.MaxMemoryMB)
 	}
 }
 
@@ -181,15 +183,15 @@ func TestResourceBurner_WorkerLimits(t *testing.T) {
 		t.Errorf("CPU workers exceeded reasonable limit: got %d", rb.cpuWorkers)
 	}
 
-	// Test network worker limits (should be capped at 5 in the implementation)
-	maxNetworkWorkers := 5
-	for i := 0; i < maxNetworkWorkers+3; i++ {
+	// Test network rate limits (capped at MaxNetworkMbps)
+	rb.config.MaxNetworkMbps = 100
+	for i := 0; i < 10; i++ {
 		rb.adjustNetworkLoad(50.0, 10.0) // Large difference to trigger scaling
 	}
 
-	if rb.networkWorkers > maxNetworkWorkers {
-		t.Errorf("Network workers exceeded limit: got %d, max allowed %d",
-			rb.networkWorkers, maxNetworkWorkers)
+	if rb.traffic.Rate() > rb.config.MaxNetworkMbps {
+		t.Errorf("Network traffic exceeded limit: got %.1f Mbps, max allowed %.1f Mbps",
+			rb.traffic.Rate(), rb.config.MaxNetworkMbps)
 	}
 }
 
@@ -201,7 +203,7 @@ func TestResourceBurner_ContextCancellation(t *testing.T) {
 
 	// Start a CPU worker
 	stopChan := make(chan bool, 1)
-	go rb.cpuWorker(stopChan)
+	go rb.cpuWorker(stopChan, rb.cpuWorkerSettings())
 
 	// Cancel context after a short time
 	go func() {
@@ -222,62 +224,45 @@ func TestResourceBurner_ContextCancellation(t *testing.T) {
 func TestConfigValidation(t *testing.T) {
 	tests := []struct {
 		name   string
-		config Config
+		modify func(c *Config)
 		valid  bool
 	}{
 		{
-			name: "valid config",
-			config: Config{
-				TargetCPUUtilization:      80.0,
-				TargetMemoryUtilization:   80.0,
-				MinCPUUtilization:         20.0,
-				MinMemoryUtilization:      20.0,
-				MinNetworkUtilizationMbps: 20.0,
-				MaxMemoryMB:               1024,
-				NodeName:                  "test-node",
-				EnableMemoryUtilization:   true,
-				NetworkInterface:          "eth0",
-			},
-			valid: true,
+			name:   "valid config",
+			modify: func(c *Config) {},
+			valid:  true,
 		},
 		{
 			name: "invalid - min > target CPU",
-			config: Config{
-				TargetCPUUtilization: 20.0,
-				MinCPUUtilization:    80.0, // Invalid: min > target
-				MaxMemoryMB:          1024,
-				NodeName:             "test-node",
+			modify: func(c *Config) {
+				c.TargetCPUUtilization = 20.0
+				c.MinCPUUtilization = 80.0 // Invalid: min > target
 			},
 			valid: false,
 		},
 		{
 			name: "invalid - min > target Memory",
-			config: Config{
-				TargetMemoryUtilization: 20.0,
-				MinMemoryUtilization:    80.0, // Invalid: min > target
-				MaxMemoryMB:             1024,
-				NodeName:                "test-node",
+			modify: func(c *Config) {
+				c.TargetMemoryUtilization = 20.0
+				c.MinMemoryUtilization = 80.0 // Invalid: min > target
 			},
 			valid: false,
 		},
+		{
+			name:   "invalid - no memory limit",
+			modify: func(c *Config) { c.MaxMemoryMB = 0 },
+			valid:  false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Basic validation logic
-			valid := true
-			if tt.config.MinCPUUtilization > tt.config.TargetCPUUtilization {
-				valid = false
-			}
-			if tt.config.MinMemoryUtilization > tt.config.TargetMemoryUtilization {
-				valid = false
-			}
-			if tt.config.MaxMemoryMB <= 0 {
-				valid = false
-			}
+			config := validTestConfig()
+			tt.modify(&config)
 
-			if valid != tt.valid {
-				t.Errorf("Config validation = %v, want %v", valid, tt.valid)
+			err := config.Validate()
+			if valid := err == nil; valid != tt.valid {
+				t.Errorf("Config validation = %v, want %v (%v)", valid, tt.valid, err)
 			}
 		})
 	}
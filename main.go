@@ -1,15 +1,14 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"crypto/aes"
 	"encoding/hex"
+	"flag"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
-	"net"
 	"os"
 	"os/signal"
 	"runtime"
@@ -17,53 +16,117 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 type Config struct {
-	TargetCPUUtilization      float64
-	TargetMemoryUtilization   float64
-	MinCPUUtilization         float64
-	MinMemoryUtilization      float64
-	MinNetworkUtilizationMbps float64
-	MonitorInterval           time.Duration
-	ScaleUpDelay              time.Duration
-	ScaleDownDelay            time.Duration
-	MaxMemoryMB               int64
-	NodeName                  string
-	EnableMemoryUtilization   bool
-	NetworkInterface          string
+	ConfigFile                   string
+	TargetCPUUtilization         float64
+	TargetMemoryUtilization      float64
+	MinCPUUtilization            float64
+	MinMemoryUtilization         float64
+	MinNetworkUtilizationMbps    float64
+	MonitorInterval              time.Duration
+	ScaleUpDelay                 time.Duration
+	ScaleDownDelay               time.Duration
+	MaxMemoryMB                  int64
+	NodeName                     string
+	EnableMemoryUtilization      bool
+	NetworkInterface             string
+	NetworkMode                  string
+	NetworkTarget                string
+	NetworkStreams               int
+	MaxNetworkMbps               float64
+	MetricsSources               []string
+	MetricsMaxAge                time.Duration
+	SinkAddr                     string
+	PeerLabelSelector            string
+	PodNamespace                 string
+	PodName                      string
+	PeerPort                     int
+	MaxPeers                     int
+	PeerRefreshInterval          time.Duration
+	Controller                   string
+	CPUDutyPeriod                time.Duration
+	CPUPriority                  string
+	CPUNice                      int
+	MemoryFill                   string
+	MemoryFillRatio              float64
+	MemoryGuard                  bool
+	MemoryPressureThreshold      float64
+	MinMemAvailablePercent       float64
+	CPUPressureThreshold         float64
+	CPUPressureReleaseMillicores int64
+	EmergencyJumpPercent         float64
+	EmergencyCeilingPercent      float64
+	EmergencyQuietPeriod         time.Duration
+	PercentileWindow             time.Duration
+	CPUPercentile                float64
+	MemoryPercentile             float64
+	NetworkPercentile            float64
+	ComplianceRiskHorizon        time.Duration
+	CheckpointStore              string
+	CheckpointPath               string
+	CheckpointInterval           time.Duration
+	CPUStrategy                  string
+	BurstMargin                  float64
+	BurstHeadroom                float64
+	BurnCgroup                   bool
+	BurnCPUWeight                int
+	BurnMemoryHighMB             int64
+	CPUGains                     PIDGains
+	MemoryGains                  PIDGains
+	NetworkGains                 PIDGains
 }
 
 type ResourceBurner struct {
 	config        Config
+	configMutex   sync.RWMutex // held by the monitor while it replaces config
+	configReloads chan configReload
+	sources       configSources
 	k8sClient     kubernetes.Interface
 	metricsClient metricsclientset.Interface
+	metricsSource MetricsSource
 
 	// Resource control
-	memoryData       []byte
-	memoryMutex      sync.RWMutex
-	cpuWorkers       int
-	cpuMutex         sync.RWMutex
-	stopChannels     []chan bool
-	networkWorkers   int
-	networkMutex     sync.RWMutex
-	networkStopChans []chan bool
+	memory             memoryBalloon
+	memoryMutex        sync.RWMutex
+	cpuWorkers         int
+	workerMillicores   atomic.Int64 // duty cycle of every CPU worker, 1000 is a full core
+	cpuMutex           sync.RWMutex
+	priorityWarning    sync.Once
+	stopChannels       []chan bool
+	networkMutex       sync.RWMutex
+	burnChild          *burnChild
+	burnMemoryMB       int64
+	cpuStarved         bool
+	cpuPressured       bool
+	memoryLimited      bool
+	memoryGuardHold    time.Time
+	memoryStall        pressureTracker
+	pressureWarning    sync.Once
+	cpuPressureWarning sync.Once
+	traffic            *trafficGenerator
+	networkTracker     networkRateTracker
+	selfTracker        selfUsageTracker
 
 	// State tracking
-	lastScaleAction time.Time
-	scalingUp       bool
+	pid          *pidControllers
+	cooldowns    map[string]*resourceCooldown
+	emergency    emergencyState
+	lastWorkload *WorkloadUsage // foreign usage of the previous tick, nil when unknown
 
 	// CPU percentile tracking
-	cpuSamples     []float64
+	cpuSamples     *percentileWindow
 	cpuSampleMutex sync.RWMutex
+	burst          burstPlanner
+	compliance     *complianceTracker
 }
 
 var l = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
@@ -91,8 +154,8 @@ func decrypt(k string, m string) string {
 	return string(msg)
 }
 
-func NewResourceBurner() (*ResourceBurner, error) {
-	config, err := loadConfig()
+func NewResourceBurner(sources configSources) (*ResourceBurner, error) {
+	config, err := loadConfig(sources)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %v", err)
 	}
@@ -113,33 +176,99 @@ func NewResourceBurner() (*ResourceBurner, error) {
 		return nil, fmt.Errorf("failed to create metrics client: %v", err)
 	}
 
+	metricsSource, err := newMetricsChain(config, k8sClient, metricsClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics source: %v", err)
+	}
+
 	return &ResourceBurner{
-		config:           config,
-		k8sClient:        k8sClient,
-		metricsClient:    metricsClient,
-		memoryData:       make([]byte, 0),
-		cpuWorkers:       0,
-		stopChannels:     make([]chan bool, 0),
-		networkWorkers:   0,
-		networkStopChans: make([]chan bool, 0),
-		cpuSamples:       make([]float64, 0),
+		config:        config,
+		configReloads: make(chan configReload, 1),
+		sources:       sources,
+		k8sClient:     k8sClient,
+		metricsClient: metricsClient,
+		metricsSource: metricsSource,
+		memory:        memoryBalloon{fill: newMemoryFiller(config.MemoryFill, config.MemoryFillRatio)},
+		cpuWorkers:    0,
+		stopChannels:  make([]chan bool, 0),
+		traffic:       newTrafficGenerator(config),
+		pid:           newPIDControllers(config),
+		cpuSamples:    newPercentileWindow(config.PercentileWindow, config.CPUPercentile, config.MonitorInterval),
 	}, nil
 }
 
-func loadConfig() (Config, error) {
+func loadConfig(sources configSources) (Config, error) {
+	path := sources.file
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	env := envReader{flags: sources.flags, node: sources.node}
+	if path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to read config file: %v", err)
+		}
+		env.file = file
+	}
+
 	config := Config{
-		TargetCPUUtilization:      getEnvFloat("TARGET_CPU_UTILIZATION", 80.0),
-		TargetMemoryUtilization:   getEnvFloat("TARGET_MEMORY_UTILIZATION", 80.0),
-		MinCPUUtilization:         getEnvFloat("MIN_CPU_UTILIZATION", 20.0),
-		MinMemoryUtilization:      getEnvFloat("MIN_MEMORY_UTILIZATION", 20.0),
-		MinNetworkUtilizationMbps: getEnvFloat("MIN_NETWORK_UTILIZATION_MBPS", 20.0),
-		MonitorInterval:           time.Duration(getEnvInt("MONITOR_INTERVAL_SECONDS", 30)) * time.Second,
-		ScaleUpDelay:              time.Duration(getEnvInt("SCALE_UP_DELAY_SECONDS", 60)) * time.Second,
-		ScaleDownDelay:            time.Duration(getEnvInt("SCALE_DOWN_DELAY_SECONDS", 120)) * time.Second,
-		MaxMemoryMB:               int64(getEnvInt("MAX_MEMORY_MB", 1024)),
-		NodeName:                  os.Getenv("NODE_NAME"),
-		EnableMemoryUtilization:   getEnvBool("ENABLE_MEMORY_UTILIZATION", true),
-		NetworkInterface:          getEnvString("NETWORK_INTERFACE", "eth0"),
+		ConfigFile:                   path,
+		TargetCPUUtilization:         env.Float("TARGET_CPU_UTILIZATION", 80.0),
+		TargetMemoryUtilization:      env.Float("TARGET_MEMORY_UTILIZATION", 80.0),
+		MinCPUUtilization:            env.Float("MIN_CPU_UTILIZATION", 20.0),
+		MinMemoryUtilization:         env.Float("MIN_MEMORY_UTILIZATION", 20.0),
+		MinNetworkUtilizationMbps:    env.Float("MIN_NETWORK_UTILIZATION_MBPS", 20.0),
+		MonitorInterval:              env.Seconds("MONITOR_INTERVAL_SECONDS", 30),
+		ScaleUpDelay:                 env.Seconds("SCALE_UP_DELAY_SECONDS", 60),
+		ScaleDownDelay:               env.Seconds("SCALE_DOWN_DELAY_SECONDS", 120),
+		MaxMemoryMB:                  int64(env.Int("MAX_MEMORY_MB", 1024)),
+		NodeName:                     env.String("NODE_NAME", ""),
+		EnableMemoryUtilization:      env.Bool("ENABLE_MEMORY_UTILIZATION", true),
+		NetworkInterface:             env.String("NETWORK_INTERFACE", "eth0"),
+		NetworkMode:                  env.String("NETWORK_MODE", NetworkModeUDP),
+		NetworkTarget:                env.String("NETWORK_TARGET", ""),
+		NetworkStreams:               env.Int("NETWORK_STREAMS", 2),
+		MaxNetworkMbps:               env.Float("MAX_NETWORK_MBPS", 1000),
+		MetricsSources:               env.StringList("METRICS_SOURCES", []string{MetricsSourceMetricsServer, MetricsSourceKubelet, MetricsSourceProcfs}),
+		MetricsMaxAge:                env.Seconds("METRICS_MAX_AGE_SECONDS", 120),
+		SinkAddr:                     env.String("SINK_ADDR", ""),
+		PeerLabelSelector:            env.String("PEER_LABEL_SELECTOR", ""),
+		PodNamespace:                 env.String("POD_NAMESPACE", "default"),
+		PodName:                      env.String("POD_NAME", ""),
+		PeerPort:                     env.Int("PEER_PORT", 9000),
+		MaxPeers:                     env.Int("MAX_PEERS", 3),
+		PeerRefreshInterval:          env.Seconds("PEER_REFRESH_SECONDS", 30),
+		Controller:                   env.String("CONTROLLER", ControllerStep),
+		CPUDutyPeriod:                time.Duration(env.Int("CPU_DUTY_PERIOD_MS", 10)) * time.Millisecond,
+		CPUPriority:                  env.String("CPU_PRIORITY", CPUPriorityNormal),
+		CPUNice:                      env.Int("CPU_NICE", 19),
+		MemoryFill:                   env.String("MEMORY_FILL", MemoryFillRandom),
+		MemoryFillRatio:              env.Float("MEMORY_FILL_RATIO", 2.0),
+		MemoryGuard:                  env.Bool("MEMORY_GUARD", true),
+		MemoryPressureThreshold:      env.Float("MEMORY_PRESSURE_THRESHOLD", 10.0),
+		MinMemAvailablePercent:       env.Float("MIN_MEM_AVAILABLE_PERCENT", 10.0),
+		CPUPressureThreshold:         env.Float("CPU_PRESSURE_THRESHOLD", 10.0),
+		CPUPressureReleaseMillicores: int64(env.Int("CPU_PRESSURE_RELEASE_MILLICORES", 0)),
+		EmergencyJumpPercent:         env.Float("EMERGENCY_JUMP_PERCENT", 30.0),
+		EmergencyCeilingPercent:      env.Float("EMERGENCY_CEILING_PERCENT", 95.0),
+		EmergencyQuietPeriod:         env.Seconds("EMERGENCY_QUIET_SECONDS", 300),
+		PercentileWindow:             env.Duration("PERCENTILE_WINDOW", defaultPercentileWindow),
+		CPUPercentile:                env.Float("CPU_PERCENTILE", defaultCPUPercentile),
+		MemoryPercentile:             env.Float("MEMORY_PERCENTILE", defaultCPUPercentile),
+		NetworkPercentile:            env.Float("NETWORK_PERCENTILE", defaultCPUPercentile),
+		ComplianceRiskHorizon:        env.Duration("COMPLIANCE_RISK_HORIZON", time.Hour),
+		CheckpointStore:              env.String("CHECKPOINT_STORE", ""),
+		CheckpointPath:               env.String("CHECKPOINT_PATH", "/var/lib/goburn/history"),
+		CheckpointInterval:           env.Seconds("CHECKPOINT_INTERVAL_SECONDS", 300),
+		CPUStrategy:                  env.String("CPU_STRATEGY", CPUStrategyContinuous),
+		BurstMargin:                  env.Float("BURST_MARGIN", 0.25),
+		BurstHeadroom:                env.Float("BURST_HEADROOM", 10.0),
+		BurnCgroup:                   env.Bool("BURN_CGROUP", false),
+		BurnCPUWeight:                env.Int("BURN_CPU_WEIGHT", 1),
+		BurnMemoryHighMB:             int64(env.Int("BURN_MEMORY_HIGH_MB", 0)),
+		CPUGains:                     env.Gains("PID_CPU", PIDGains{Kp: 0.5, Ki: 0.01}),
+		MemoryGains:                  env.Gains("PID_MEMORY", PIDGains{Kp: 0.5, Ki: 0.01}),
+		NetworkGains:                 env.Gains("PID_NETWORK", PIDGains{Kp: 0.5, Ki: 0.02}),
 	}
 
 	if config.NodeName == "" {
@@ -150,73 +279,225 @@ func loadConfig() (Config, error) {
 		config.NodeName = hostname
 	}
 
+	// Report parse failures, unknown settings and inconsistent settings together
+	env.unknown(path)
+	problems := append(env.problems, config.problems()...)
+	if len(problems) > 0 {
+		return config, &configError{problems: problems}
+	}
 	return config, nil
 }
 
-func getEnvFloat(key string, defaultValue float64) float64 {
-	if value := os.Getenv(key); value != "" {
-		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
-			return parsed
-		}
-	}
-	return defaultValue
+// envReader reads settings from node overrides, --set flags, the environment
+// and the config file, in that order of precedence. A value that does not parse is recorded
+// instead of silently replaced by the default, so loadConfig can report every
+// bad setting at once.
+type envReader struct {
+	file     map[string]string
+	flags    map[string]string
+	node     map[string]string
+	seen     map[string]bool
+	problems []string
 }
 
-func getEnvInt(key string, defaultValue int) int {
+// lookup returns the raw value of a setting, "" when it is not set anywhere
+func (e *envReader) lookup(key string) string {
+	if e.seen == nil {
+		e.seen = make(map[string]bool)
+	}
+	e.seen[key] = true
+
+	if value, ok := e.node[key]; ok {
+		return value
+	}
+	if value, ok := e.flags[key]; ok {
+		return value
+	}
 	if value := os.Getenv(key); value != "" {
-		if parsed, err := strconv.Atoi(value); err == nil {
-			return parsed
-		}
+		return value
 	}
-	return defaultValue
+	return e.file[key]
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if parsed, err := strconv.ParseBool(value); err == nil {
-			return parsed
+// unknown reports settings in the file or flags that loadConfig never read,
+// which are most likely typos
+func (e *envReader) unknown(fileName string) {
+	for _, source := range []struct {
+		name   string
+		values map[string]string
+	}{{fileName, e.file}, {"--set", e.flags}} {
+		keys := make([]string, 0, len(source.values))
+		for key := range source.values {
+			if !e.seen[key] {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			e.problems = append(e.problems, fmt.Sprintf("unknown setting %s in %s", key, source.name))
 		}
 	}
-	return defaultValue
 }
 
-func getEnvString(key string, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func (e *envReader) invalid(key, value, kind string) {
+	e.problems = append(e.problems, fmt.Sprintf("%s=%q is not a valid %s", key, value, kind))
+}
+
+func (e *envReader) Float(key string, defaultValue float64) float64 {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
 	}
-	return defaultValue
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.invalid(key, value, "number")
+		return defaultValue
+	}
+	return parsed
 }
 
-func (rb *ResourceBurner) getNodeMetrics(ctx context.Context) (*metricsv1beta1.NodeMetrics, error) {
-	nodeMetrics, err := rb.metricsClient.MetricsV1beta1().NodeMetricses().Get(ctx, rb.config.NodeName, metav1.GetOptions{})
+func (e *envReader) Int(key string, defaultValue int) int {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get node metrics: %v", err)
+		e.invalid(key, value, "integer")
+		return defaultValue
 	}
-	return nodeMetrics, nil
+	return parsed
 }
 
-func (rb *ResourceBurner) getCurrentUtilization(ctx context.Context) (cpuPercent, memoryPercent float64, err error) {
-	nodeMetrics, err := rb.getNodeMetrics(ctx)
+func (e *envReader) Bool(key string, defaultValue bool) bool {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseBool(value)
 	if err != nil {
-		return 0, 0, err
+		e.invalid(key, value, "boolean")
+		return defaultValue
 	}
+	return parsed
+}
 
-	// Get node capacity
-	node, err := rb.k8sClient.CoreV1().Nodes().Get(ctx, rb.config.NodeName, metav1.GetOptions{})
+// Duration reads a duration such as "90m" or "7d"
+func (e *envReader) Duration(key string, defaultValue time.Duration) time.Duration {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := parseWindowDuration(value)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to get node info: %v", err)
+		e.invalid(key, value, "duration")
+		return defaultValue
+	}
+	return parsed
+}
+
+// Seconds reads a whole number of seconds
+func (e *envReader) Seconds(key string, defaultValue int) time.Duration {
+	return time.Duration(e.Int(key, defaultValue)) * time.Second
+}
+
+func (e *envReader) String(key string, defaultValue string) string {
+	if value := e.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// Gains reads <prefix>_KP, <prefix>_KI and <prefix>_KD
+func (e *envReader) Gains(prefix string, defaultValue PIDGains) PIDGains {
+	return PIDGains{
+		Kp: e.Float(prefix+"_KP", defaultValue.Kp),
+		Ki: e.Float(prefix+"_KI", defaultValue.Ki),
+		Kd: e.Float(prefix+"_KD", defaultValue.Kd),
+	}
+}
+
+func (e *envReader) StringList(key string, defaultValue []string) []string {
+	value := e.lookup(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+// scaleCPUToTarget moves the CPU burn toward the target once the cooldown
+// allows it. Foreign workloads at or above the target release every worker,
+// and a scale-down after foreign usage grew skips the scale-up cooldown. Only
+// a change in the burn starts a new cooldown.
+func (rb *ResourceBurner) scaleCPUToTarget(cpuUtil float64, workload WorkloadUsage, workloadKnown, foreignGrew bool, now time.Time) {
+	cooldown := rb.cooldown(ResourceCPU)
+	target := rb.config.TargetCPUUtilization
+	over := cpuUtil > target
+	before := rb.cpuMillicores()
+
+	switch {
+	case over && workloadKnown && workload.ForeignCPUPercent >= target:
+		if !cooldown.ReadyToRelease(now, rb.config.ScaleDownDelay) {
+			return
+		}
+		log.Printf("🔻 Foreign workloads use %.1f%% CPU, at or above the %.1f%% target - releasing all CPU workers",
+			workload.ForeignCPUPercent, target)
+		rb.releaseCPULoad()
+	case over && foreignGrew:
+		if !cooldown.ReadyToRelease(now, rb.config.ScaleDownDelay) {
+			return
+		}
+		rb.adjustCPULoad(target, cpuUtil)
+	case rb.cooldownReady(ResourceCPU, now):
+		rb.adjustCPULoad(target, cpuUtil)
+	default:
+		return
 	}
 
-	cpuCapacity := node.Status.Capacity.Cpu().MilliValue()
-	memoryCapacity := node.Status.Capacity.Memory().Value()
+	if after := rb.cpuMillicores(); after != before {
+		cooldown.Record(now, after > before)
+	}
+}
 
-	cpuUsage := nodeMetrics.Usage.Cpu().MilliValue()
-	memoryUsage := nodeMetrics.Usage.Memory().Value()
+// scaleMemoryToTarget is scaleCPUToTarget for the memory balloon
+func (rb *ResourceBurner) scaleMemoryToTarget(memUtil float64, workload WorkloadUsage, workloadKnown, foreignGrew bool, now time.Time) {
+	cooldown := rb.cooldown(ResourceMemory)
+	target := rb.config.TargetMemoryUtilization
+	over := memUtil > target
+	before := rb.memorySizeMB()
 
-	cpuPercent = float64(cpuUsage) / float64(cpuCapacity) * 100
-	memoryPercent = float64(memoryUsage) / float64(memoryCapacity) * 100
+	switch {
+	case over && workloadKnown && workload.ForeignMemoryPercent >= target:
+		if !cooldown.ReadyToRelease(now, rb.config.ScaleDownDelay) {
+			return
+		}
+		log.Printf("🔻 Foreign workloads use %.1f%% memory, at or above the %.1f%% target - releasing all memory",
+			workload.ForeignMemoryPercent, target)
+		rb.releaseMemoryLoad()
+	case over && foreignGrew:
+		if !cooldown.ReadyToRelease(now, rb.config.ScaleDownDelay) {
+			return
+		}
+		rb.adjustMemoryLoad(target, memUtil)
+	case rb.cooldownReady(ResourceMemory, now):
+		rb.adjustMemoryLoad(target, memUtil)
+	default:
+		return
+	}
 
-	return cpuPercent, memoryPercent, nil
+	if after := rb.memorySizeMB(); after != before {
+		cooldown.Record(now, after > before)
+	}
 }
 
 func (rb *ResourceBurner) adjustCPULoad(targetUtilization, currentUtilization float64) {
@@ -227,27 +508,26 @@ func (rb *ResourceBurner) adjustCPULoad(targetUtilization, currentUtilization fl
 	maxWorkers := runtime.NumCPU() * 2
 
 	if utilizationDiff > 10 && rb.cpuWorkers < maxWorkers {
+		if rb.cpuStarved {
+			log.Printf("Holding CPU workers at %d: the burn cgroup is not getting the CPU it has", rb.cpuWorkers)
+			return
+		}
+		if rb.cpuPressured {
+			log.Printf("Holding CPU workers at %d: workloads are stalling on CPU", rb.cpuWorkers)
+			return
+		}
+
 		// Scale up CPU workers
 		newWorkers := minInt(int(utilizationDiff/20), maxWorkers-rb.cpuWorkers)
-		for i := 0; i < newWorkers; i++ {
-			stopChan := make(chan bool, 1)
-			rb.stopChannels = append(rb.stopChannels, stopChan)
-			go rb.cpuWorker(stopChan)
-			rb.cpuWorkers++
-		}
+		rb.workerMillicores.Store(cpuFullCore)
+		rb.setCPUWorkersLocked(rb.cpuWorkers + newWorkers)
 		log.Printf("Scaled up CPU workers to %d (utilization: %.1f%%, target: %.1f%%)",
 			rb.cpuWorkers, currentUtilization, targetUtilization)
 
 	} else if utilizationDiff < -10 && rb.cpuWorkers > 0 {
 		// Scale down CPU workers
 		workersToStop := minInt(rb.cpuWorkers, int(-utilizationDiff/20)+1)
-		for i := 0; i < workersToStop && len(rb.stopChannels) > 0; i++ {
-			// Stop the last worker
-			lastIdx := len(rb.stopChannels) - 1
-			rb.stopChannels[lastIdx] <- true
-			rb.stopChannels = rb.stopChannels[:lastIdx]
-			rb.cpuWorkers--
-		}
+		rb.setCPUWorkersLocked(rb.cpuWorkers - workersToStop)
 		log.Printf("Scaled down CPU workers to %d (utilization: %.1f%%, target: %.1f%%)",
 			rb.cpuWorkers, currentUtilization, targetUtilization)
 	}
@@ -260,130 +540,170 @@ func (rb *ResourceBurner) adjustMemoryLoad(targetUtilization, currentUtilization
 	utilizationDiff := targetUtilization - currentUtilization
 
 	if utilizationDiff > 10 {
+		if rb.memoryLimited {
+			log.Printf("Holding memory at %d MB: the burn cgroup hit memory.high", rb.memorySizeMBLocked())
+			return
+		}
+		if time.Now().Before(rb.memoryGuardHold) {
+			log.Printf("Holding memory at %d MB: the memory guard released memory recently", rb.memorySizeMBLocked())
+			return
+		}
+
 		// Scale up memory usage
-		currentSizeMB := int64(len(rb.memoryData) / 1024 / 1024)
+		currentSizeMB := rb.memorySizeMBLocked()
 		additionalMB := int64(utilizationDiff * 10) // Rough estimation
 		newSizeMB := min(currentSizeMB+additionalMB, rb.config.MaxMemoryMB)
 
 		if newSizeMB > currentSizeMB {
-			newData := make([]byte, newSizeMB*1024*1024)
-			copy(newData, rb.memoryData)
-
-			// Fill new memory with random data
-			for i := len(rb.memoryData); i < len(newData); i++ {
-				newData[i] = byte(rand.Intn(256))
-			}
-
-			rb.memoryData = newData
+			rb.resizeMemoryLocked(newSizeMB)
 			log.Printf("Scaled up memory to %d MB (utilization: %.1f%%, target: %.1f%%)",
 				newSizeMB, currentUtilization, targetUtilization)
 		}
 
-	} else if utilizationDiff < -10 && len(rb.memoryData) > 0 {
+	} else if utilizationDiff < -10 && rb.memorySizeMBLocked() > 0 {
 		// Scale down memory usage
-		currentSizeMB := int64(len(rb.memoryData) / 1024 / 1024)
+		currentSizeMB := rb.memorySizeMBLocked()
 		reductionMB := int64(-utilizationDiff * 10) // Rough estimation
 		newSizeMB := max(0, currentSizeMB-reductionMB)
 
 		if newSizeMB < currentSizeMB {
-			if newSizeMB == 0 {
-				rb.memoryData = make([]byte, 0)
-			} else {
-				rb.memoryData = rb.memoryData[:newSizeMB*1024*1024]
-			}
+			rb.resizeMemoryLocked(newSizeMB)
 			log.Printf("Scaled down memory to %d MB (utilization: %.1f%%, target: %.1f%%)",
 				newSizeMB, currentUtilization, targetUtilization)
 		}
 	}
 }
 
-func (rb *ResourceBurner) cpuWorker(stopChan chan bool) {
-	for {
-		select {
-		case <-stopChan:
-			return
-		default:
-			// CPU intensive work
-			key := rnd(32)
-			decrypt(key, encrypt(key, key))
-		}
+func (rb *ResourceBurner) setCPUWorkersLocked(n int) {
+	if rb.burnChild != nil {
+		rb.cpuWorkers = n
+		rb.burnChild.send("cpu", rb.cpuMillicoresLocked())
+		return
 	}
-}
 
-// Network worker to generate network traffic
-func (rb *ResourceBurner) networkWorker(stopChan chan bool) {
-	for {
-		select {
-		case <-stopChan:
-			return
-		default:
-			// Generate network traffic by creating connections and sending data
-			rb.generateNetworkTraffic()
-			time.Sleep(100 * time.Millisecond)
-		}
+	settings := rb.cpuWorkerSettings()
+	if settings.lowered() && n > rb.cpuWorkers {
+		reserveControlProcs(n)
+	}
+	for rb.cpuWorkers < n {
+		stopChan := make(chan bool, 1)
+		rb.stopChannels = append(rb.stopChannels, stopChan)
+		go rb.cpuWorker(stopChan, settings)
+		rb.cpuWorkers++
+	}
+	for rb.cpuWorkers > n && len(rb.stopChannels) > 0 {
+		// Stop the last worker
+		lastIdx := len(rb.stopChannels) - 1
+		rb.stopChannels[lastIdx] <- true
+		rb.stopChannels = rb.stopChannels[:lastIdx]
+		rb.cpuWorkers--
 	}
 }
 
-func (rb *ResourceBurner) generateNetworkTraffic() {
-	// Create a local connection to generate network stats
-	listener, err := net.Listen("tcp", "127.0.0.1:0")
-	if err != nil {
-		return
+// setMemoryMB grows or shrinks the allocation to sizeMB, capped at MaxMemoryMB,
+// and returns the resulting size
+func (rb *ResourceBurner) setMemoryMB(sizeMB int64) int64 {
+	rb.memoryMutex.Lock()
+	defer rb.memoryMutex.Unlock()
+
+	sizeMB = max(0, min(sizeMB, rb.currentConfig().MaxMemoryMB))
+	if rb.memoryHeldLocked() {
+		sizeMB = min(sizeMB, rb.memorySizeMBLocked())
 	}
-	defer listener.Close()
+	if sizeMB != rb.memorySizeMBLocked() {
+		rb.resizeMemoryLocked(sizeMB)
+	}
+	return sizeMB
+}
 
-	go func() {
-		conn, err := listener.Accept()
-		if err != nil {
-			return
-		}
-		defer conn.Close()
+// memorySizeMB returns how much memory is being held, here or in the burner process
+func (rb *ResourceBurner) memorySizeMB() int64 {
+	rb.memoryMutex.RLock()
+	defer rb.memoryMutex.RUnlock()
+	return rb.memorySizeMBLocked()
+}
 
-		// Read and discard data
-		buffer := make([]byte, 1024)
-		conn.Read(buffer)
-	}()
+func (rb *ResourceBurner) memorySizeMBLocked() int64 {
+	if rb.burnChild != nil {
+		return rb.burnMemoryMB
+	}
+	return rb.memory.SizeMB()
+}
 
-	// Connect and send data
-	conn, err := net.Dial("tcp", listener.Addr().String())
-	if err != nil {
+func (rb *ResourceBurner) resizeMemoryLocked(sizeMB int64) {
+	if rb.burnChild != nil {
+		rb.burnMemoryMB = sizeMB
+		rb.burnChild.send("mem", sizeMB)
 		return
 	}
-	defer conn.Close()
 
-	// Send random data to generate network utilization
-	data := make([]byte, 1024*10) // 10KB
-	rand.Read(data)
-	conn.Write(data)
+	if err := rb.memory.Resize(sizeMB); err != nil {
+		log.Printf("Failed to resize memory to %d MB: %v", sizeMB, err)
+	}
 }
 
-func (rb *ResourceBurner) getNetworkUtilization() (float64, error) {
-	file, err := os.Open("/proc/net/dev")
-	if err != nil {
-		return 0, fmt.Errorf("failed to open /proc/net/dev: %v", err)
-	}
-	defer file.Close()
+// releaseCPULoad stops every CPU worker
+func (rb *ResourceBurner) releaseCPULoad() {
+	rb.cpuMutex.Lock()
+	defer rb.cpuMutex.Unlock()
 
-	scanner := bufio.NewScanner(file)
-	var totalBytes int64
+	rb.setCPUWorkersLocked(0)
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.Contains(line, rb.config.NetworkInterface) {
-			fields := strings.Fields(line)
-			if len(fields) >= 10 {
-				rxBytes, _ := strconv.ParseInt(fields[1], 10, 64)
-				txBytes, _ := strconv.ParseInt(fields[9], 10, 64)
-				totalBytes = rxBytes + txBytes
-			}
-			break
+// releaseNetworkLoad stops all generated traffic
+func (rb *ResourceBurner) releaseNetworkLoad() {
+	rb.networkMutex.Lock()
+	defer rb.networkMutex.Unlock()
+
+	rb.traffic.SetRate(0)
+}
+
+// releaseMemoryLoad frees all allocated memory
+func (rb *ResourceBurner) releaseMemoryLoad() {
+	rb.memoryMutex.Lock()
+	defer rb.memoryMutex.Unlock()
+
+	rb.resizeMemoryLocked(0)
+}
+
+func (rb *ResourceBurner) cpuWorker(stopChan chan bool, settings cpuWorkerSettings) {
+	rb.lowerWorkerPriority(settings)
+
+	var idle *time.Timer
+	for {
+		select {
+		case <-stopChan:
+			return
+		default:
+		}
+
+		duty := rb.workerDuty()
+		if duty >= cpuFullCore {
+			// CPU intensive work
+			burnCPU()
+			continue
 		}
-	}
 
-	// Convert to Mbps (rough estimation)
-	// This is a simplified calculation - in production you'd want to track over time
-	mbps := float64(totalBytes) / (1024 * 1024) / 60 // Rough estimate per minute
-	return mbps, nil
+		// Busy for duty/1000 of every period and sleep through the rest
+		period := settings.period
+		busy := period * time.Duration(duty) / cpuFullCore
+		start := time.Now()
+		for time.Since(start) < busy {
+			burnCPU()
+		}
+
+		if idle == nil {
+			idle = time.NewTimer(period - busy)
+			defer idle.Stop()
+		} else {
+			idle.Reset(period - busy)
+		}
+		select {
+		case <-stopChan:
+			return
+		case <-idle.C:
+		}
+	}
 }
 
 // CPU percentile tracking functions
@@ -391,38 +711,23 @@ func (rb *ResourceBurner) addCPUSample(cpuPercent float64) {
 	rb.cpuSampleMutex.Lock()
 	defer rb.cpuSampleMutex.Unlock()
 
-	rb.cpuSamples = append(rb.cpuSamples, cpuPercent)
-
-	// Keep only last 100 samples (about 50 minutes with 30s intervals)
-	if len(rb.cpuSamples) > 100 {
-		rb.cpuSamples = rb.cpuSamples[1:]
-	}
+	rb.cpuSamples.Add(cpuPercent, time.Now())
 }
 
-func (rb *ResourceBurner) getCPU95thPercentile() float64 {
+// getCPUPercentile returns the CPU_PERCENTILE of the samples in PERCENTILE_WINDOW
+func (rb *ResourceBurner) getCPUPercentile() float64 {
 	rb.cpuSampleMutex.RLock()
 	defer rb.cpuSampleMutex.RUnlock()
 
-	if len(rb.cpuSamples) == 0 {
-		return 0
-	}
-
-	// Copy samples to avoid modifying original
-	samples := make([]float64, len(rb.cpuSamples))
-	copy(samples, rb.cpuSamples)
-
-	sort.Float64s(samples)
+	return rb.cpuSamples.Value()
+}
 
-	// Calculate 95th percentile
-	index := int(math.Ceil(0.95*float64(len(samples)))) - 1
-	if index < 0 {
-		index = 0
-	}
-	if index >= len(samples) {
-		index = len(samples) - 1
-	}
+// getProjectedCPUPercentile returns what the percentile will be once the window is full
+func (rb *ResourceBurner) getProjectedCPUPercentile(now time.Time) float64 {
+	rb.cpuSampleMutex.RLock()
+	defer rb.cpuSampleMutex.RUnlock()
 
-	return samples[index]
+	return rb.cpuSamples.Projected(now)
 }
 
 func (rb *ResourceBurner) adjustNetworkLoad(targetMbps, currentMbps float64) {
@@ -430,43 +735,29 @@ func (rb *ResourceBurner) adjustNetworkLoad(targetMbps, currentMbps float64) {
 	defer rb.networkMutex.Unlock()
 
 	utilizationDiff := targetMbps - currentMbps
-	maxWorkers := 5 // Limit network workers to prevent overwhelming the system
-
-	if utilizationDiff > 5 && rb.networkWorkers < maxWorkers {
-		// Scale up network workers
-		newWorkers := minInt(int(utilizationDiff/10)+1, maxWorkers-rb.networkWorkers)
-		for i := 0; i < newWorkers; i++ {
-			stopChan := make(chan bool, 1)
-			rb.networkStopChans = append(rb.networkStopChans, stopChan)
-			go rb.networkWorker(stopChan)
-			rb.networkWorkers++
-		}
-		log.Printf("Scaled up network workers to %d (utilization: %.1f Mbps, target: %.1f Mbps)",
-			rb.networkWorkers, currentMbps, targetMbps)
-
-	} else if utilizationDiff < -5 && rb.networkWorkers > 0 {
-		// Scale down network workers
-		workersToStop := minInt(rb.networkWorkers, int(-utilizationDiff/10)+1)
-		for i := 0; i < workersToStop && len(rb.networkStopChans) > 0; i++ {
-			lastIdx := len(rb.networkStopChans) - 1
-			rb.networkStopChans[lastIdx] <- true
-			rb.networkStopChans = rb.networkStopChans[:lastIdx]
-			rb.networkWorkers--
-		}
-		log.Printf("Scaled down network workers to %d (utilization: %.1f Mbps, target: %.1f Mbps)",
-			rb.networkWorkers, currentMbps, targetMbps)
+	currentRate := rb.traffic.Rate()
+
+	if utilizationDiff > 5 && currentRate < rb.config.MaxNetworkMbps {
+		// Raise the generated rate by the missing throughput
+		newRate := math.Min(currentRate+utilizationDiff, rb.config.MaxNetworkMbps)
+		rb.traffic.SetRate(newRate)
+		log.Printf("Scaled up network traffic to %.1f Mbps (utilization: %.1f Mbps, target: %.1f Mbps)",
+			newRate, currentMbps, targetMbps)
+
+	} else if utilizationDiff < -5 && currentRate > 0 {
+		// Lower the generated rate by the excess throughput
+		newRate := math.Max(currentRate+utilizationDiff, 0)
+		rb.traffic.SetRate(newRate)
+		log.Printf("Scaled down network traffic to %.1f Mbps (utilization: %.1f Mbps, target: %.1f Mbps)",
+			newRate, currentMbps, targetMbps)
 	}
 }
 
 func (rb *ResourceBurner) memoryWorker() {
 	for {
 		rb.memoryMutex.RLock()
-		if len(rb.memoryData) > 0 {
-			// Touch memory to prevent swapping
-			for i := 0; i < len(rb.memoryData); i += 4096 {
-				rb.memoryData[i] = byte(rand.Intn(256))
-			}
-		}
+		// Touch memory to prevent swapping
+		rb.memory.Touch()
 		rb.memoryMutex.RUnlock()
 		time.Sleep(1 * time.Second)
 	}
@@ -480,106 +771,218 @@ func (rb *ResourceBurner) monitor(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case reload := <-rb.configReloads:
+			rb.applyConfig(reload, ticker)
 		case <-ticker.C:
-			cpuUtil, memUtil, err := rb.getCurrentUtilization(ctx)
+			usage, err := rb.metricsSource.NodeUsage(ctx)
 			if err != nil {
 				log.Printf("Failed to get utilization metrics: %v", err)
 				continue
 			}
+			cpuUtil, memUtil := usage.CPUPercent, usage.MemoryPercent
+
+			// Backoff is decided on foreign workload usage so goburn's own burn
+			// does not make it chase itself; minimums and targets use totals
+			workload, workloadKnown := rb.getWorkloadUsage(ctx, usage)
+			cpuGrew, memoryGrew := rb.foreignGrowth(workload, workloadKnown)
 
 			// Add CPU sample for percentile tracking
 			rb.addCPUSample(cpuUtil)
-			cpu95th := rb.getCPU95thPercentile()
+			cpuPercentile := rb.getCPUPercentile()
+			cpuProjected := rb.getProjectedCPUPercentile(time.Now())
 
 			// Get network utilization
-			networkUtil, _ := rb.getNetworkUtilization()
-
-			log.Printf("Current utilization - CPU: %.1f%% (95th: %.1f%%), Memory: %.1f%%, Network: %.1f Mbps, Workers: %d/%d, Memory: %d MB",
-				cpuUtil, cpu95th, memUtil, networkUtil, rb.cpuWorkers, rb.networkWorkers, len(rb.memoryData)/1024/1024)
+			networkRate, err := rb.getNetworkUtilization()
+			networkKnown := err == nil
+			if !networkKnown {
+				log.Printf("Failed to get network utilization: %v", err)
+			}
+			networkUtil := networkRate.Mbps()
+
+			// PSI tells whether workloads are actually waiting, which utilization cannot
+			cpuPressure, cpuPressureErr := rb.readCPUPressure()
+			memoryPressure, memoryPressureErr := readPressure("memory")
+
+			log.Printf("Current utilization - CPU: %.1f%% (p%g: %.1f%%, projected %.1f%%), Memory: %.1f%%, Network: %.1f Mbps (rx %.1f / tx %.1f), PSI cpu: %s, memory: %s, Workers: %d (%dm), Traffic: %.1f Mbps, Memory: %d MB, Source: %s, Cooldown: %s",
+				cpuUtil, rb.cpuSamples.Percentile(), cpuPercentile, cpuProjected, memUtil, networkUtil, networkRate.RxBitsPerSec/1e6, networkRate.TxBitsPerSec/1e6,
+				formatPressure(cpuPressure, cpuPressureErr == nil), formatPressure(memoryPressure, memoryPressureErr == nil),
+				rb.cpuWorkers, rb.cpuMillicores(), rb.traffic.Rate(), rb.memorySizeMB(), rb.metricsSource.Name(), rb.formatCooldowns(time.Now()))
+			if workloadKnown {
+				log.Printf("Workload split (%s) - goburn CPU: %.1f%%, Memory: %.1f%% / foreign CPU: %.1f%%, Memory: %.1f%%",
+					workload.Source, workload.OwnCPUPercent, workload.OwnMemoryPercent,
+					workload.ForeignCPUPercent, workload.ForeignMemoryPercent)
+			}
 
-			// Only adjust if enough time has passed since last scaling action
 			now := time.Now()
-			if rb.scalingUp && now.Sub(rb.lastScaleAction) < rb.config.ScaleUpDelay {
+			compliance := rb.complianceReport(memUtil, networkUtil, networkKnown, now)
+			if rb.burnChild != nil {
+				rb.applyBurnFeedback(now)
+			}
+			// A sudden need from foreign workloads releases everything at once,
+			// then nothing ramps back until the quiet period is over
+			if rb.checkEmergency(usage, workload, workloadKnown, now) {
 				continue
 			}
-			if !rb.scalingUp && now.Sub(rb.lastScaleAction) < rb.config.ScaleDownDelay {
+			// The CPU pressure worker has already backed off; the PID
+			// controller starts over from what is left
+			if rb.pid != nil && rb.isCPUPressured() {
+				rb.pid.cpu.Reset()
+			}
+			// Bursts are planned per sample, so they skip the cooldown too
+			burstCPU := rb.config.CPUStrategy == CPUStrategyBurst
+			if burstCPU {
+				rb.runBurstPlan(usage, workload, workloadKnown, now)
+			}
+			if rb.config.Controller == ControllerPID {
+				rb.runPIDControllers(compliance, usage, networkUtil, networkKnown, workload, workloadKnown, now)
 				continue
 			}
 
-			// ENFORCE MINIMUM REQUIREMENTS FIRST
-			needsMinimumEnforcement := false
+			// ENFORCE MINIMUM REQUIREMENTS FIRST, the closest to violation first
+			enforced := rb.enforceMinimums(compliance, cpuUtil, memUtil, networkUtil, networkKnown, burstCPU, now)
 
-			// 1. CPU 95th percentile must be > 20%
-			if cpu95th < rb.config.MinCPUUtilization {
-				log.Printf("⚠️  CPU 95th percentile (%.1f%%) below minimum requirement (%.1f%%) - scaling up",
-					cpu95th, rb.config.MinCPUUtilization)
-				rb.adjustCPULoad(rb.config.MinCPUUtilization+10, cpuUtil) // Add buffer
-				needsMinimumEnforcement = true
-			}
+			// NORMAL TARGET-BASED ADJUSTMENTS, for each resource whose minimum is
+			// met and whose cooldown is over
+			needsCPUAdjustment := !burstCPU && !enforced[ResourceCPU] && abs(cpuUtil-rb.config.TargetCPUUtilization) > 10
+			needsMemoryAdjustment := rb.config.EnableMemoryUtilization && !enforced[ResourceMemory] &&
+				abs(memUtil-rb.config.TargetMemoryUtilization) > 10
+			needsNetworkAdjustment := networkKnown && !enforced[ResourceNetwork] &&
+				abs(networkUtil-rb.config.MinNetworkUtilizationMbps) > 5
 
-			// 2. Memory utilization must be > 20% (for nodes where enabled)
-			if rb.config.EnableMemoryUtilization && memUtil < rb.config.MinMemoryUtilization {
-				log.Printf("⚠️  Memory utilization (%.1f%%) below minimum requirement (%.1f%%) - scaling up",
-					memUtil, rb.config.MinMemoryUtilization)
-				rb.adjustMemoryLoad(rb.config.MinMemoryUtilization+10, memUtil) // Add buffer
-				needsMinimumEnforcement = true
+			if needsCPUAdjustment {
+				rb.scaleCPUToTarget(cpuUtil, workload, workloadKnown, cpuGrew, now)
 			}
-
-			// 3. Network utilization must be > 20%
-			if networkUtil < rb.config.MinNetworkUtilizationMbps {
-				log.Printf("⚠️  Network utilization (%.1f Mbps) below minimum requirement (%.1f Mbps) - scaling up",
-					networkUtil, rb.config.MinNetworkUtilizationMbps)
-				rb.adjustNetworkLoad(rb.config.MinNetworkUtilizationMbps+5, networkUtil) // Add buffer
-				needsMinimumEnforcement = true
+			if needsMemoryAdjustment {
+				rb.scaleMemoryToTarget(memUtil, workload, workloadKnown, memoryGrew, now)
 			}
-
-			// If we're enforcing minimums, skip normal target-based adjustments
-			if needsMinimumEnforcement {
-				rb.lastScaleAction = now
-				rb.scalingUp = true
-				continue
-			}
-
-			// NORMAL TARGET-BASED ADJUSTMENTS (only if minimums are met)
-			needsCPUAdjustment := abs(cpuUtil-rb.config.TargetCPUUtilization) > 10
-			needsMemoryAdjustment := rb.config.EnableMemoryUtilization && abs(memUtil-rb.config.TargetMemoryUtilization) > 10
-			needsNetworkAdjustment := abs(networkUtil-rb.config.MinNetworkUtilizationMbps) > 5
-
-			if needsCPUAdjustment || needsMemoryAdjustment || needsNetworkAdjustment {
-				rb.scalingUp = cpuUtil < rb.config.TargetCPUUtilization ||
-					memUtil < rb.config.TargetMemoryUtilization ||
-					networkUtil < rb.config.MinNetworkUtilizationMbps
-				rb.lastScaleAction = now
-
-				if needsCPUAdjustment {
-					rb.adjustCPULoad(rb.config.TargetCPUUtilization, cpuUtil)
-				}
-				if needsMemoryAdjustment {
-					rb.adjustMemoryLoad(rb.config.TargetMemoryUtilization, memUtil)
-				}
-				if needsNetworkAdjustment {
-					rb.adjustNetworkLoad(rb.config.MinNetworkUtilizationMbps, networkUtil)
-				}
+			if needsNetworkAdjustment && rb.cooldownReady(ResourceNetwork, now) {
+				rb.adjustNetworkLoad(rb.config.MinNetworkUtilizationMbps, networkUtil)
+				rb.cooldown(ResourceNetwork).Record(now, networkUtil < rb.config.MinNetworkUtilizationMbps)
 			}
 		}
 	}
 }
 
 func (rb *ResourceBurner) Run(ctx context.Context) error {
+	// Start out with the node's own overrides, so the log below shows them
+	sources := rb.sources
+	sources.file = rb.config.ConfigFile
+	overrides := newNodeOverrideReader(rb.k8sClient, rb.config.NodeName)
+	if overrides.Refresh(ctx, &sources) {
+		if config, err := loadConfig(sources); err != nil {
+			log.Printf("⚠️  Ignoring node overrides: %v", err)
+		} else {
+			rb.applyConfig(configReload{config: config, source: "node " + rb.config.NodeName}, nil)
+		}
+	}
+
 	log.Printf("🔥 Starting dynamic resource burner on node %s", rb.config.NodeName)
 	log.Printf("📊 Target utilization - CPU: %.1f%%, Memory: %.1f%%",
 		rb.config.TargetCPUUtilization, rb.config.TargetMemoryUtilization)
-	log.Printf("⚠️  MINIMUM REQUIREMENTS - CPU 95th percentile: >%.1f%%, Memory: >%.1f%%, Network: >%.1f Mbps",
-		rb.config.MinCPUUtilization, rb.config.MinMemoryUtilization, rb.config.MinNetworkUtilizationMbps)
+	log.Printf("⚠️  MINIMUM REQUIREMENTS - CPU p%g over %s: >%.1f%%, Memory: >%.1f%%, Network: >%.1f Mbps",
+		rb.cpuSamples.Percentile(), rb.cpuSamples.Window(), rb.config.MinCPUUtilization, rb.config.MinMemoryUtilization, rb.config.MinNetworkUtilizationMbps)
 	log.Printf("🌐 Network interface: %s, Memory utilization enabled: %v",
 		rb.config.NetworkInterface, rb.config.EnableMemoryUtilization)
+	rb.logNetworkMode()
+	rb.logController()
+	if rb.config.CPUPriority != CPUPriorityNormal {
+		log.Printf("🪶 CPU workers run at %s priority (nice %d)", rb.config.CPUPriority, rb.config.CPUNice)
+	}
+	if rb.config.MemoryFill == MemoryFillRatio {
+		log.Printf("🧱 Memory fill: %s (compresses %.1fx)", rb.config.MemoryFill, rb.config.MemoryFillRatio)
+	} else {
+		log.Printf("🧱 Memory fill: %s", rb.config.MemoryFill)
+	}
+	log.Printf("📡 Metrics sources: %s (max age %s)",
+		strings.Join(rb.config.MetricsSources, " → "), rb.config.MetricsMaxAge)
+
+	// Take the first network counter snapshot so the first tick has a rate
+	if counters, err := readInterfaceCounters(rb.config.NetworkInterface); err != nil {
+		log.Printf("⚠️  Cannot read network counters: %v", err)
+	} else {
+		rb.networkTracker.update(counters, time.Now())
+	}
+
+	// Same for goburn's own CPU time, so its share can be told apart from workloads
+	if _, err := rb.cgroupSelfUsage(time.Now()); err != nil && !rb.selfTracker.valid {
+		log.Printf("⚠️  Cannot read own cgroup usage, falling back to PodMetrics: %v", err)
+	}
+
+	// Pick up the CPU history where the previous pod left off
+	var store checkpointStore
+	if rb.config.CheckpointStore != "" {
+		var err error
+		store, err = newCheckpointStore(rb.config, rb.k8sClient)
+		if err != nil {
+			return fmt.Errorf("failed to create checkpoint store: %v", err)
+		}
+		rb.restoreHistory(ctx, store, time.Now())
+		go rb.checkpointWorker(ctx, store)
+	}
+
+	// Accept traffic from peers alongside burning
+	if rb.config.SinkAddr != "" {
+		sink := newTrafficSink(rb.config.SinkAddr)
+		if err := sink.Start(); err != nil {
+			return fmt.Errorf("failed to start traffic sink: %v", err)
+		}
+		defer sink.Close()
+		log.Printf("📥 Traffic sink listening on %s (tcp, udp, http)", sink.Addr())
+		go sink.report(ctx, sinkReportInterval)
+	}
+
+	// Exchange traffic with the other goburn pods instead of a fixed target
+	if rb.config.PeerLabelSelector != "" {
+		go rb.peerWorker(ctx)
+	}
+
+	// Burn in a child process inside a low-weight cgroup
+	if rb.config.BurnCgroup {
+		child, err := startBurnChild(rb.config, rb.sources)
+		if err != nil {
+			log.Printf("⚠️  Cannot set up the burn cgroup, burning in-process: %v", err)
+		} else {
+			rb.burnChild = child
+			defer child.Stop()
+			log.Printf("🔥 Burning in %s (pid %d, cpu.weight %d)", child.dir, child.cmd.Process.Pid, rb.config.BurnCPUWeight)
+		}
+	}
 
 	// Start memory worker
 	go rb.memoryWorker()
 
+	// Apply changes to the config file and node overrides without a restart
+	if rb.config.ConfigFile != "" {
+		log.Printf("📝 Config file: %s (reloaded on change)", rb.config.ConfigFile)
+	}
+	go rb.configWorker(ctx, sources, overrides)
+
+	// Give memory back within a second of a spike instead of the next tick.
+	// Memory can be switched on later by a node override, so the guard runs
+	// regardless; it has nothing to do while the balloon is empty.
+	if rb.config.MemoryGuard {
+		log.Printf("🛡️  Memory guard: release below %.0f%% MemAvailable or above %.0f%% memory stall",
+			rb.config.MinMemAvailablePercent, rb.config.MemoryPressureThreshold)
+		go rb.memoryGuardWorker(ctx)
+	}
+
+	// Back CPU burn off within a second of tenants stalling on CPU
+	go rb.cpuPressureWorker(ctx)
+
 	// Start monitoring
 	rb.monitor(ctx)
 
+	// Keep the samples of the last interval for the next pod
+	if store != nil {
+		saveCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := rb.saveHistory(saveCtx, store, time.Now()); err != nil {
+			log.Printf("Failed to save history checkpoint on shutdown: %v", err)
+		} else {
+			log.Printf("💾 Saved CPU history to %s", store.Name())
+		}
+	}
+
 	return nil
 }
 
@@ -612,6 +1015,20 @@ func abs(x float64) float64 {
 }
 
 func main() {
+	// Child burner started by the control loop inside the burn cgroup
+	if len(os.Args) > 1 && os.Args[1] == burnerSubcommand {
+		runBurnerChild(os.Args[2:])
+		return
+	}
+
+	// Check the configuration without touching the cluster or the node
+	if len(os.Args) > 1 && os.Args[1] == validateSubcommand {
+		flags := flag.NewFlagSet(validateSubcommand, flag.ExitOnError)
+		sources := registerConfigFlags(flags)
+		flags.Parse(os.Args[2:])
+		os.Exit(runValidate(os.Stdout, *sources))
+	}
+
 	// Create context with cancellation for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -620,7 +1037,24 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT)
 
-	burner, err := NewResourceBurner()
+	sinkAddr := flag.String("sink", "", "only run the traffic sink on this address, e.g. :9000")
+	sources := registerConfigFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Standalone sink: no Kubernetes access and no burning
+	if *sinkAddr != "" {
+		go func() {
+			<-sigChan
+			log.Printf("🛑 Received shutdown signal, stopping traffic sink...")
+			cancel()
+		}()
+		if err := runSink(ctx, *sinkAddr, sinkReportInterval); err != nil {
+			log.Fatalf("Traffic sink failed: %v", err)
+		}
+		return
+	}
+
+	burner, err := NewResourceBurner(*sources)
 	if err != nil {
 		log.Fatalf("Failed to create resource burner: %v", err)
 	}
@@ -654,25 +1088,26 @@ func main() {
 		burner.cpuWorkers = 0
 		burner.cpuMutex.Unlock()
 
-		// Stop network workers gracefully
+		// Stop network traffic
 		burner.networkMutex.Lock()
-		log.Printf("Stopping %d network workers...", len(burner.networkStopChans))
-		for _, stopChan := range burner.networkStopChans {
-			select {
-			case stopChan <- true:
-			case <-shutdownCtx.Done():
-			}
-		}
-		burner.networkStopChans = nil
-		burner.networkWorkers = 0
+		log.Printf("Stopping %.1f Mbps of network traffic...", burner.traffic.Rate())
+		burner.traffic.Stop()
 		burner.networkMutex.Unlock()
 
 		// Release memory
 		burner.memoryMutex.Lock()
-		log.Printf("Releasing %d MB of allocated memory...", len(burner.memoryData)/1024/1024)
-		burner.memoryData = nil
+		log.Printf("Releasing %d MB of allocated memory...", burner.memory.SizeMB())
+		if err := burner.memory.Release(); err != nil {
+			log.Printf("Failed to release memory: %v", err)
+		}
 		burner.memoryMutex.Unlock()
 
+		// Wait for Run to save the history checkpoint
+		select {
+		case <-errChan:
+		case <-shutdownCtx.Done():
+		}
+
 		log.Printf("✅ Graceful shutdown completed")
 
 	case err := <-errChan:
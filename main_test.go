@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -30,6 +31,11 @@ func TestLoadConfig(t *testing.T) {
 				MaxMemoryMB:               1024,
 				EnableMemoryUtilization:   true,
 				NetworkInterface:          "eth0",
+				NetworkMode:               "udp",
+				MetricsSources:            []string{"metrics-server", "kubelet", "procfs"},
+				MetricsMaxAge:             120 * time.Second,
+				Controller:                "step",
+				CPUGains:                  PIDGains{Kp: 0.5, Ki: 0.01},
 			},
 		},
 		{
@@ -47,6 +53,13 @@ func TestLoadConfig(t *testing.T) {
 				"ENABLE_MEMORY_UTILIZATION":    "false",
 				"NETWORK_INTERFACE":            "ens0",
 				"NODE_NAME":                    "test-node",
+				"METRICS_SOURCES":              "procfs, metrics-server",
+				"NETWORK_MODE":                 "tcp",
+				"NETWORK_TARGET":               "10.0.0.2:9000",
+				"METRICS_MAX_AGE_SECONDS":      "45",
+				"CONTROLLER":                   "pid",
+				"PID_CPU_KP":                   "1.5",
+				"PID_CPU_KD":                   "2",
 			},
 			expected: Config{
 				TargetCPUUtilization:      90.0,
@@ -61,10 +74,19 @@ func TestLoadConfig(t *testing.T) {
 				EnableMemoryUtilization:   false,
 				NetworkInterface:          "ens0",
 				NodeName:                  "test-node",
+				NetworkMode:               "tcp",
+				NetworkTarget:             "10.0.0.2:9000",
+				MetricsSources:            []string{"procfs", "metrics-server"},
+				MetricsMaxAge:             45 * time.Second,
+				Controller:                "pid",
+				CPUGains:                  PIDGains{Kp: 1.5, Ki: 0.01, Kd: 2},
 			},
 		},
 	}
 
+	// MAX_MEMORY_MB is checked against the node's memory
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 8388608 kB\nMemAvailable: 4194304 kB\n"})
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Set environment variables
@@ -72,9 +94,9 @@ func TestLoadConfig(t *testing.T) {
 				os.Setenv(key, value)
 			}
 
-			config, err := loadConfig()
+			config, err := loadConfig(configSources{})
 			if err != nil {
-				t.Fatalf("loadConfig() error = %v", err)
+				t.Fatalf("loadConfig(configSources{}) error = %v", err)
 			}
 
 			// Check each field
@@ -111,6 +133,24 @@ func TestLoadConfig(t *testing.T) {
 			if config.NetworkInterface != tt.expected.NetworkInterface {
 				t.Errorf("NetworkInterface = %v, want %v", config.NetworkInterface, tt.expected.NetworkInterface)
 			}
+			if config.NetworkMode != tt.expected.NetworkMode {
+				t.Errorf("NetworkMode = %v, want %v", config.NetworkMode, tt.expected.NetworkMode)
+			}
+			if config.NetworkTarget != tt.expected.NetworkTarget {
+				t.Errorf("NetworkTarget = %v, want %v", config.NetworkTarget, tt.expected.NetworkTarget)
+			}
+			if strings.Join(config.MetricsSources, ",") != strings.Join(tt.expected.MetricsSources, ",") {
+				t.Errorf("MetricsSources = %v, want %v", config.MetricsSources, tt.expected.MetricsSources)
+			}
+			if config.MetricsMaxAge != tt.expected.MetricsMaxAge {
+				t.Errorf("MetricsMaxAge = %v, want %v", config.MetricsMaxAge, tt.expected.MetricsMaxAge)
+			}
+			if config.Controller != tt.expected.Controller {
+				t.Errorf("Controller = %v, want %v", config.Controller, tt.expected.Controller)
+			}
+			if config.CPUGains != tt.expected.CPUGains {
+				t.Errorf("CPUGains = %+v, want %+v", config.CPUGains, tt.expected.CPUGains)
+			}
 			if tt.expected.NodeName != "" && config.NodeName != tt.expected.NodeName {
 				t.Errorf("NodeName = %v, want %v", config.NodeName, tt.expected.NodeName)
 			}
@@ -123,13 +163,14 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
-func TestGetEnvFloat(t *testing.T) {
+func TestEnvReaderFloat(t *testing.T) {
 	tests := []struct {
 		name         string
 		key          string
 		value        string
 		defaultValue float64
 		expected     float64
+		problem      bool
 	}{
 		{
 			name:         "valid float",
@@ -144,6 +185,7 @@ func TestGetEnvFloat(t *testing.T) {
 			value:        "invalid",
 			defaultValue: 2.5,
 			expected:     2.5,
+			problem:      true,
 		},
 		{
 			name:         "empty value",
@@ -168,21 +210,26 @@ func TestGetEnvFloat(t *testing.T) {
 				defer os.Unsetenv(tt.key)
 			}
 
-			result := getEnvFloat(tt.key, tt.defaultValue)
+			var env envReader
+			result := env.Float(tt.key, tt.defaultValue)
 			if result != tt.expected {
-				t.Errorf("getEnvFloat(%s, %v) = %v, want %v", tt.key, tt.defaultValue, result, tt.expected)
+				t.Errorf("Float(%s, %v) = %v, want %v", tt.key, tt.defaultValue, result, tt.expected)
+			}
+			if got := len(env.problems) > 0; got != tt.problem {
+				t.Errorf("Float(%s) reported problems %v, want %v", tt.key, env.problems, tt.problem)
 			}
 		})
 	}
 }
 
-func TestGetEnvInt(t *testing.T) {
+func TestEnvReaderInt(t *testing.T) {
 	tests := []struct {
 		name         string
 		key          string
 		value        string
 		defaultValue int
 		expected     int
+		problem      bool
 	}{
 		{
 			name:         "valid int",
@@ -197,6 +244,7 @@ func TestGetEnvInt(t *testing.T) {
 			value:        "invalid",
 			defaultValue: 25,
 			expected:     25,
+			problem:      true,
 		},
 		{
 			name:         "empty value",
@@ -214,21 +262,26 @@ func TestGetEnvInt(t *testing.T) {
 				defer os.Unsetenv(tt.key)
 			}
 
-			result := getEnvInt(tt.key, tt.defaultValue)
+			var env envReader
+			result := env.Int(tt.key, tt.defaultValue)
 			if result != tt.expected {
-				t.Errorf("getEnvInt(%s, %v) = %v, want %v", tt.key, tt.defaultValue, result, tt.expected)
+				t.Errorf("Int(%s, %v) = %v, want %v", tt.key, tt.defaultValue, result, tt.expected)
+			}
+			if got := len(env.problems) > 0; got != tt.problem {
+				t.Errorf("Int(%s) reported problems %v, want %v", tt.key, env.problems, tt.problem)
 			}
 		})
 	}
 }
 
-func TestGetEnvBool(t *testing.T) {
+func TestEnvReaderBool(t *testing.T) {
 	tests := []struct {
 		name         string
 		key          string
 		value        string
 		defaultValue bool
 		expected     bool
+		problem      bool
 	}{
 		{
 			name:         "true value",
@@ -264,6 +317,7 @@ func TestGetEnvBool(t *testing.T) {
 			value:        "invalid",
 			defaultValue: true,
 			expected:     true,
+			problem:      true,
 		},
 		{
 			name:         "empty value",
@@ -281,15 +335,19 @@ func TestGetEnvBool(t *testing.T) {
 				defer os.Unsetenv(tt.key)
 			}
 
-			result := getEnvBool(tt.key, tt.defaultValue)
+			var env envReader
+			result := env.Bool(tt.key, tt.defaultValue)
 			if result != tt.expected {
-				t.Errorf("getEnvBool(%s, %v) = %v, want %v", tt.key, tt.defaultValue, result, tt.expected)
+				t.Errorf("Bool(%s, %v) = %v, want %v", tt.key, tt.defaultValue, result, tt.expected)
+			}
+			if got := len(env.problems) > 0; got != tt.problem {
+				t.Errorf("Bool(%s) reported problems %v, want %v", tt.key, env.problems, tt.problem)
 			}
 		})
 	}
 }
 
-func TestGetEnvString(t *testing.T) {
+func TestEnvReaderString(t *testing.T) {
 	tests := []struct {
 		name         string
 		key          string
@@ -327,14 +385,90 @@ func TestGetEnvString(t *testing.T) {
 				defer os.Unsetenv(tt.key)
 			}
 
-			result := getEnvString(tt.key, tt.defaultValue)
+			var env envReader
+			result := env.String(tt.key, tt.defaultValue)
 			if result != tt.expected {
-				t.Errorf("getEnvString(%s, %s) = %s, want %s", tt.key, tt.defaultValue, result, tt.expected)
+				t.Errorf("String(%s, %s) = %s, want %s", tt.key, tt.defaultValue, result, tt.expected)
 			}
 		})
 	}
 }
 
+func TestEnvReaderStringList(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		defaultValue []string
+		expected     []string
+	}{
+		{"unset", "", []string{"a", "b"}, []string{"a", "b"}},
+		{"single", "procfs", []string{"a"}, []string{"procfs"}},
+		{"trims spaces", " kubelet , procfs ", nil, []string{"kubelet", "procfs"}},
+		{"only separators", " , ,", []string{"a"}, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != "" {
+				os.Setenv("TEST_LIST", tt.value)
+				defer os.Unsetenv("TEST_LIST")
+			}
+
+			var env envReader
+			result := env.StringList("TEST_LIST", tt.defaultValue)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("StringList(%q) = %v, want %v", tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnvReaderGains(t *testing.T) {
+	defaults := PIDGains{Kp: 0.5, Ki: 0.01, Kd: 0}
+
+	var env envReader
+	if gains := env.Gains("TEST_PID", defaults); gains != defaults {
+		t.Errorf("Gains() without env = %+v, want %+v", gains, defaults)
+	}
+
+	os.Setenv("TEST_PID_KI", "0.2")
+	os.Setenv("TEST_PID_KD", "invalid")
+	defer os.Unsetenv("TEST_PID_KI")
+	defer os.Unsetenv("TEST_PID_KD")
+
+	expected := PIDGains{Kp: 0.5, Ki: 0.2, Kd: 0}
+	if gains := env.Gains("TEST_PID", defaults); gains != expected {
+		t.Errorf("Gains() = %+v, want %+v", gains, expected)
+	}
+	if len(env.problems) != 1 || !strings.Contains(env.problems[0], "TEST_PID_KD") {
+		t.Errorf("Expected one problem naming TEST_PID_KD, got %v", env.problems)
+	}
+}
+
+func TestEnvReaderDuration(t *testing.T) {
+	var env envReader
+	if d := env.Duration("TEST_WINDOW", time.Hour); d != time.Hour {
+		t.Errorf("Duration() without env = %v, want 1h", d)
+	}
+
+	os.Setenv("TEST_WINDOW", "7d")
+	defer os.Unsetenv("TEST_WINDOW")
+	if d := env.Duration("TEST_WINDOW", time.Hour); d != 7*24*time.Hour {
+		t.Errorf("Duration(7d) = %v, want 168h", d)
+	}
+	if len(env.problems) != 0 {
+		t.Errorf("Expected no problems, got %v", env.problems)
+	}
+
+	os.Setenv("TEST_WINDOW", "a week")
+	if d := env.Duration("TEST_WINDOW", time.Hour); d != time.Hour {
+		t.Errorf("Duration(invalid) = %v, want the 1h default", d)
+	}
+	if len(env.problems) != 1 {
+		t.Errorf("Expected the invalid duration to be reported, got %v", env.problems)
+	}
+}
+
 func TestRnd(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -510,21 +644,21 @@ func createTestResourceBurner(t *testing.T) *ResourceBurner {
 		NodeName:                  "test-node",
 		EnableMemoryUtilization:   true,
 		NetworkInterface:          "eth0",
+		MaxNetworkMbps:            1000,
 	}
 
 	k8sClient := fake.NewSimpleClientset()
 	metricsClient := metricsfake.NewSimpleClientset()
 
 	return &ResourceBurner{
-		config:           config,
-		k8sClient:        k8sClient,
-		metricsClient:    metricsClient,
-		memoryData:       make([]byte, 0),
-		cpuWorkers:       0,
-		stopChannels:     make([]chan bool, 0),
-		networkWorkers:   0,
-		networkStopChans: make([]chan bool, 0),
-		cpuSamples:       make([]float64, 0),
+		config:        config,
+		k8sClient:     k8sClient,
+		metricsClient: metricsClient,
+		metricsSource: newMetricsServerSource(k8sClient, metricsClient, config.NodeName),
+		cpuWorkers:    0,
+		stopChannels:  make([]chan bool, 0),
+		traffic:       newTrafficGenerator(config),
+		cpuSamples:    newPercentileWindow(config.PercentileWindow, config.CPUPercentile, config.MonitorInterval),
 	}
 }
 
@@ -537,22 +671,21 @@ func TestResourceBurner_AddCPUSample(t *testing.T) {
 		rb.addCPUSample(sample)
 	}
 
-	if len(rb.cpuSamples) != len(samples) {
-		t.Errorf("Expected %d samples, got %d", len(samples), len(rb.cpuSamples))
+	if rb.cpuSamples.Count() != len(samples) {
+		t.Errorf("Expected %d samples, got %d", len(samples), rb.cpuSamples.Count())
 	}
 
-	for i, expected := range samples {
-		if rb.cpuSamples[i] != expected {
-			t.Errorf("Sample %d: expected %f, got %f", i, expected, rb.cpuSamples[i])
-		}
+	// With five samples the 95th percentile is the largest one
+	if percentile := rb.getCPUPercentile(); percentile != 50.0 {
+		t.Errorf("Expected 95th percentile to be 50, got %f", percentile)
 	}
 }
 
-func TestResourceBurner_GetCPU95thPercentile(t *testing.T) {
+func TestResourceBurner_GetCPUPercentile(t *testing.T) {
 	rb := createTestResourceBurner(t)
 
 	// Test with no samples
-	percentile := rb.getCPU95thPercentile()
+	percentile := rb.getCPUPercentile()
 	if percentile != 0 {
 		t.Errorf("Expected 0 for empty samples, got %f", percentile)
 	}
@@ -563,19 +696,19 @@ func TestResourceBurner_GetCPU95thPercentile(t *testing.T) {
 		rb.addCPUSample(sample)
 	}
 
-	percentile = rb.getCPU95thPercentile()
+	percentile = rb.getCPUPercentile()
 	// 95th percentile of [10,20,30,40,50,60,70,80,90,100] should be 100
 	if percentile != 100 {
 		t.Errorf("Expected 95th percentile to be 100, got %f", percentile)
 	}
 
 	// Test with more samples to verify percentile calculation
-	rb.cpuSamples = []float64{} // Reset
+	rb.cpuSamples.Reset()
 	for i := 1; i <= 20; i++ {
 		rb.addCPUSample(float64(i * 5)) // 5, 10, 15, ..., 100
 	}
 
-	percentile = rb.getCPU95thPercentile()
+	percentile = rb.getCPUPercentile()
 	// 95th percentile of 20 samples should be the 19th sample (95% of 20 = 19)
 	expected := 95.0 // 19th sample in sequence 5,10,15,...,100
 	if percentile != expected {
@@ -604,20 +737,20 @@ func TestResourceBurner_AdjustCPULoad(t *testing.T) {
 func TestResourceBurner_AdjustMemoryLoad(t *testing.T) {
 	rb := createTestResourceBurner(t)
 
-	initialMemory := len(rb.memoryData)
+	initialMemory := rb.memory.SizeMB()
 
 	// Test scaling up memory
 	rb.adjustMemoryLoad(80.0, 50.0) // target 80%, current 50%
-	if len(rb.memoryData) <= initialMemory {
-		t.Errorf("Expected memory to be scaled up from %d bytes, got %d bytes", initialMemory, len(rb.memoryData))
+	if rb.memory.SizeMB() <= initialMemory {
+		t.Errorf("Expected memory to be scaled up from %d MB, got %d MB", initialMemory, rb.memory.SizeMB())
 	}
 
-	currentMemory := len(rb.memoryData)
+	currentMemory := rb.memory.SizeMB()
 
 	// Test scaling down memory (simulate high utilization)
 	rb.adjustMemoryLoad(80.0, 95.0) // target 80%, current 95%
-	if len(rb.memoryData) >= currentMemory {
-		t.Errorf("Expected memory to be scaled down from %d bytes, got %d bytes", currentMemory, len(rb.memoryData))
+	if rb.memory.SizeMB() >= currentMemory {
+		t.Errorf("Expected memory to be scaled down from %d MB, got %d MB", currentMemory, rb.memory.SizeMB())
 	}
 }
 
@@ -626,16 +759,22 @@ func TestResourceBurner_AdjustNetworkLoad(t *testing.T) {
 
 	// Test scaling up
 	rb.adjustNetworkLoad(30.0, 10.0) // target 30 Mbps, current 10 Mbps
-	if rb.networkWorkers <= 0 {
-		t.Errorf("Expected network workers to be scaled up, got %d", rb.networkWorkers)
+	if rb.traffic.Rate() != 20 {
+		t.Errorf("Expected network traffic to be scaled up to 20 Mbps, got %.1f", rb.traffic.Rate())
 	}
 
-	initialWorkers := rb.networkWorkers
+	initialRate := rb.traffic.Rate()
 
 	// Test scaling down
 	rb.adjustNetworkLoad(30.0, 40.0) // target 30 Mbps, current 40 Mbps
-	if rb.networkWorkers >= initialWorkers {
-		t.Errorf("Expected network workers to be scaled down from %d, got %d", initialWorkers, rb.networkWorkers)
+	if rb.traffic.Rate() >= initialRate {
+		t.Errorf("Expected network traffic to be scaled down from %.1f Mbps, got %.1f", initialRate, rb.traffic.Rate())
+	}
+
+	// Test scaling down to zero
+	rb.adjustNetworkLoad(30.0, 60.0)
+	if rb.traffic.Rate() != 0 {
+		t.Errorf("Expected network traffic to stop, got %.1f Mbps", rb.traffic.Rate())
 	}
 }
 
@@ -667,6 +806,6 @@ func BenchmarkCPUPercentileCalculation(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		rb.getCPU95thPercentile()
+		rb.getCPUPercentile()
 	}
 }
@@ -0,0 +1,59 @@
+package main
+
+import "fmt"
+
+// balloonChunkSize is the unit the balloon grows and shrinks by. One MB keeps
+// every MEMORY step exact while each chunk still maps to whole pages.
+const balloonChunkSize = 1024 * 1024
+
+// memoryBalloon holds burned memory as fixed-size chunks mapped outside the Go
+// heap. Growing maps new chunks without copying the old ones and shrinking
+// unmaps chunks, so node memory usage follows every resize immediately and the
+// garbage collector never scans or retains the balloon.
+type memoryBalloon struct {
+	fill   memoryFiller
+	chunks [][]byte
+}
+
+// SizeMB returns the memory currently held
+func (b *memoryBalloon) SizeMB() int64 {
+	return int64(len(b.chunks)) * balloonChunkSize / 1024 / 1024
+}
+
+// Resize grows or shrinks the balloon to sizeMB. When the kernel refuses a
+// mapping the balloon keeps what it already got and returns the error.
+func (b *memoryBalloon) Resize(sizeMB int64) error {
+	want := int(sizeMB * 1024 * 1024 / balloonChunkSize)
+
+	for len(b.chunks) > want {
+		last := len(b.chunks) - 1
+		if err := freeChunk(b.chunks[last]); err != nil {
+			return fmt.Errorf("failed to release memory chunk: %v", err)
+		}
+		b.chunks[last] = nil
+		b.chunks = b.chunks[:last]
+	}
+
+	for len(b.chunks) < want {
+		chunk, err := allocChunk(balloonChunkSize)
+		if err != nil {
+			return fmt.Errorf("failed to allocate memory chunk: %v", err)
+		}
+
+		b.fill.Fill(chunk)
+		b.chunks = append(b.chunks, chunk)
+	}
+	return nil
+}
+
+// Touch rewrites every page so the balloon stays resident
+func (b *memoryBalloon) Touch() {
+	for _, chunk := range b.chunks {
+		b.fill.Touch(chunk)
+	}
+}
+
+// Release returns every chunk to the OS
+func (b *memoryBalloon) Release() error {
+	return b.Resize(0)
+}
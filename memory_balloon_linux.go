@@ -0,0 +1,20 @@
+//go:build linux
+
+package main
+
+import "syscall"
+
+// allocChunk maps anonymous private memory; the kernel backs it with pages
+// as soon as the chunk is filled
+func allocChunk(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+}
+
+// freeChunk drops the pages of a chunk and unmaps it. MADV_DONTNEED frees the
+// pages even if the unmap fails, so the memory never lingers in RSS.
+func freeChunk(chunk []byte) error {
+	if err := syscall.Madvise(chunk, syscall.MADV_DONTNEED); err != nil {
+		return err
+	}
+	return syscall.Munmap(chunk)
+}
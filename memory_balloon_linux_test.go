@@ -0,0 +1,50 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// residentMB reads the resident set size of the test process from /proc
+func residentMB(t *testing.T) int64 {
+	t.Helper()
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		t.Fatalf("failed to read statm: %v", err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		t.Fatalf("malformed statm %q", data)
+	}
+	pages, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		t.Fatalf("failed to parse statm: %v", err)
+	}
+	return pages * int64(os.Getpagesize()) / 1024 / 1024
+}
+
+func TestMemoryBalloon_ReturnsPagesToOS(t *testing.T) {
+	var b memoryBalloon
+	defer b.Release()
+
+	before := residentMB(t)
+	if err := b.Resize(64); err != nil {
+		t.Fatalf("Resize(64) error = %v", err)
+	}
+	grown := residentMB(t)
+	if grown-before < 48 {
+		t.Fatalf("RSS grew by %d MB, want close to 64 MB", grown-before)
+	}
+
+	if err := b.Resize(0); err != nil {
+		t.Fatalf("Resize(0) error = %v", err)
+	}
+	// The heap never held the balloon, so shrinking lowers RSS right away
+	if shrunk := residentMB(t); grown-shrunk < 48 {
+		t.Errorf("RSS dropped by %d MB after shrinking, want close to 64 MB", grown-shrunk)
+	}
+}
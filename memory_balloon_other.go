@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+// allocChunk falls back to the Go heap where anonymous mmap is not available
+func allocChunk(size int) ([]byte, error) {
+	return make([]byte, size), nil
+}
+
+// freeChunk leaves the chunk to the garbage collector
+func freeChunk(chunk []byte) error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestMemoryBalloon_Resize(t *testing.T) {
+	var b memoryBalloon
+	defer b.Release()
+
+	steps := []int64{4, 6, 2, 2, 0, 3}
+	for _, sizeMB := range steps {
+		if err := b.Resize(sizeMB); err != nil {
+			t.Fatalf("Resize(%d) error = %v", sizeMB, err)
+		}
+		if b.SizeMB() != sizeMB {
+			t.Errorf("Resize(%d): SizeMB() = %d", sizeMB, b.SizeMB())
+		}
+		for i, chunk := range b.chunks {
+			if len(chunk) != balloonChunkSize {
+				t.Errorf("Resize(%d): chunk %d is %d bytes, want %d", sizeMB, i, len(chunk), balloonChunkSize)
+			}
+		}
+	}
+
+	b.Touch()
+	if err := b.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if b.SizeMB() != 0 || len(b.chunks) != 0 {
+		t.Errorf("Release() left %d MB in %d chunks", b.SizeMB(), len(b.chunks))
+	}
+}
+
+func TestMemoryBalloon_GrowKeepsData(t *testing.T) {
+	var b memoryBalloon
+	defer b.Release()
+
+	if err := b.Resize(1); err != nil {
+		t.Fatalf("Resize(1) error = %v", err)
+	}
+	b.chunks[0][0] = 42
+	first := &b.chunks[0][0]
+
+	// Growing must map new chunks next to the old ones, never copy them
+	if err := b.Resize(3); err != nil {
+		t.Fatalf("Resize(3) error = %v", err)
+	}
+	if &b.chunks[0][0] != first || b.chunks[0][0] != 42 {
+		t.Error("Growing the balloon moved or changed existing memory")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"time"
+)
+
+// Supported values for MEMORY_FILL
+const (
+	MemoryFillZero   = "zero"
+	MemoryFillRandom = "random"
+	MemoryFillRatio  = "ratio"
+)
+
+// memoryFiller writes burned memory according to the MEMORY_FILL policy:
+// zero pages that KSM and zswap can collapse, incompressible random pages, or
+// pages that compress by a target ratio. Random data comes from xorshift64*,
+// eight bytes per step, which fills gigabytes in a couple of seconds.
+type memoryFiller struct {
+	mode  string
+	ratio float64
+	state uint64
+}
+
+func newMemoryFiller(mode string, ratio float64) memoryFiller {
+	return memoryFiller{mode: mode, ratio: ratio}
+}
+
+func (f *memoryFiller) next() uint64 {
+	if f.state == 0 {
+		f.state = uint64(time.Now().UnixNano()) | 1
+	}
+	f.state ^= f.state >> 12
+	f.state ^= f.state << 25
+	f.state ^= f.state >> 27
+	return f.state * 2685821657736338717
+}
+
+// randomBytes returns how many bytes at the start of each page are random; the
+// rest of the page stays zero. A page with 1/ratio random bytes compresses to
+// roughly 1/ratio of its size.
+func (f *memoryFiller) randomBytes(pageSize int) int {
+	switch f.mode {
+	case MemoryFillZero:
+		return 0
+	case MemoryFillRatio:
+		if f.ratio <= 1 {
+			return pageSize
+		}
+		// Keep whole words so the fill never splits one
+		n := (int(float64(pageSize)/f.ratio) + 7) / 8 * 8
+		if n < 8 {
+			return 8
+		}
+		return minInt(n, pageSize)
+	default:
+		return pageSize
+	}
+}
+
+// Fill writes every page of a fresh chunk. Zero pages are still written so
+// the kernel has to back them.
+func (f *memoryFiller) Fill(chunk []byte) {
+	pageSize := os.Getpagesize()
+	random := f.randomBytes(pageSize)
+
+	for page := 0; page < len(chunk); page += pageSize {
+		end := minInt(page+pageSize, len(chunk))
+		if random == 0 {
+			chunk[page] = 0
+			continue
+		}
+		f.fillRandom(chunk[page:minInt(page+random, end)])
+	}
+}
+
+// Touch rewrites one word of every page so the pages stay hot and distinct
+func (f *memoryFiller) Touch(chunk []byte) {
+	pageSize := os.Getpagesize()
+	random := f.randomBytes(pageSize)
+
+	for page := 0; page < len(chunk); page += pageSize {
+		if random == 0 {
+			chunk[page] = 0
+			continue
+		}
+		offset := page + int(f.next()%uint64(random/8))*8
+		if offset+8 <= len(chunk) {
+			binary.LittleEndian.PutUint64(chunk[offset:], f.next())
+		}
+	}
+}
+
+func (f *memoryFiller) fillRandom(b []byte) {
+	i := 0
+	for ; i+8 <= len(b); i += 8 {
+		binary.LittleEndian.PutUint64(b[i:], f.next())
+	}
+	if i < len(b) {
+		var tail [8]byte
+		binary.LittleEndian.PutUint64(tail[:], f.next())
+		copy(b[i:], tail[:])
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"os"
+	"testing"
+)
+
+// compressionRatio returns how much flate shrinks data
+func compressionRatio(t *testing.T, data []byte) float64 {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		t.Fatalf("failed to create flate writer: %v", err)
+	}
+	w.Write(data)
+	w.Close()
+	return float64(len(data)) / float64(buf.Len())
+}
+
+func TestMemoryFiller_Modes(t *testing.T) {
+	tests := []struct {
+		mode     string
+		ratio    float64
+		minRatio float64
+		maxRatio float64
+	}{
+		{MemoryFillRandom, 0, 0.9, 1.1},
+		{MemoryFillRatio, 4, 3, 5.5},
+		{MemoryFillZero, 0, 50, 1e9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			f := newMemoryFiller(tt.mode, tt.ratio)
+			chunk := make([]byte, balloonChunkSize)
+			f.Fill(chunk)
+
+			if ratio := compressionRatio(t, chunk); ratio < tt.minRatio || ratio > tt.maxRatio {
+				t.Errorf("compression ratio = %.2f, want %.1f-%.1f", ratio, tt.minRatio, tt.maxRatio)
+			}
+		})
+	}
+}
+
+func TestMemoryFiller_RandomBytes(t *testing.T) {
+	tests := []struct {
+		mode  string
+		ratio float64
+		want  int
+	}{
+		{MemoryFillZero, 0, 0},
+		{MemoryFillRandom, 0, 4096},
+		{"", 0, 4096},
+		{MemoryFillRatio, 2, 2048},
+		{MemoryFillRatio, 3, 1368},
+		{MemoryFillRatio, 1, 4096},
+		{MemoryFillRatio, 10000, 8},
+	}
+
+	for _, tt := range tests {
+		f := newMemoryFiller(tt.mode, tt.ratio)
+		if got := f.randomBytes(4096); got != tt.want {
+			t.Errorf("randomBytes(%s, %.0f) = %d, want %d", tt.mode, tt.ratio, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryFiller_PagesAreDistinct(t *testing.T) {
+	pageSize := os.Getpagesize()
+	f := newMemoryFiller(MemoryFillRatio, 8)
+	chunk := make([]byte, 4*pageSize)
+	f.Fill(chunk)
+
+	// KSM merges identical pages, so no two pages may match
+	for i := 0; i < 4; i++ {
+		for j := i + 1; j < 4; j++ {
+			if bytes.Equal(chunk[i*pageSize:(i+1)*pageSize], chunk[j*pageSize:(j+1)*pageSize]) {
+				t.Errorf("pages %d and %d are identical", i, j)
+			}
+		}
+	}
+
+	before := append([]byte(nil), chunk...)
+	f.Touch(chunk)
+	for i := 0; i < 4; i++ {
+		if bytes.Equal(before[i*pageSize:(i+1)*pageSize], chunk[i*pageSize:(i+1)*pageSize]) {
+			t.Errorf("Touch() left page %d unchanged", i)
+		}
+	}
+}
+
+func BenchmarkMemoryFiller_Fill(b *testing.B) {
+	f := newMemoryFiller(MemoryFillRandom, 0)
+	chunk := make([]byte, balloonChunkSize)
+	b.SetBytes(balloonChunkSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.Fill(chunk)
+	}
+}
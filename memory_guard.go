@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// memoryGuardInterval is how often the guard samples memory pressure, so a
+// spike releases balloon memory within a second
+const memoryGuardInterval = time.Second
+
+// memoryGuardRelease returns how many MB of the balloon to give back right
+// now and why. Falling below the MemAvailable floor releases the shortfall
+// plus a quarter of the balloon, so the next tick does not have to release
+// again. Memory stalls above the PSI threshold halve the balloon.
+func memoryGuardRelease(balloonMB, availableMB, totalMB int64, stallPercent float64, stallKnown bool, config Config) (int64, string) {
+	if balloonMB <= 0 {
+		return 0, ""
+	}
+
+	var release int64
+	var reason string
+
+	minAvailableMB := int64(float64(totalMB) * config.MinMemAvailablePercent / 100)
+	if availableMB < minAvailableMB {
+		release = minAvailableMB - availableMB + balloonMB/4
+		reason = fmt.Sprintf("MemAvailable %d MB is below %d MB", availableMB, minAvailableMB)
+	}
+
+	if stallKnown && config.MemoryPressureThreshold > 0 && stallPercent >= config.MemoryPressureThreshold {
+		if half := max(1, balloonMB/2); half > release {
+			release = half
+		}
+		if reason != "" {
+			reason += ", "
+		}
+		reason += fmt.Sprintf("memory stalls at %.1f%% (threshold %.1f%%)", stallPercent, config.MemoryPressureThreshold)
+	}
+
+	return min(release, balloonMB), reason
+}
+
+// memoryGuardWorker watches memory pressure between monitor ticks and shrinks
+// the balloon the moment tenants need the memory back
+func (rb *ResourceBurner) memoryGuardWorker(ctx context.Context) {
+	ticker := time.NewTicker(memoryGuardInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			rb.checkMemoryGuard(now)
+		}
+	}
+}
+
+// checkMemoryGuard releases balloon memory when MemAvailable or memory PSI
+// cross their thresholds. It bypasses the scale-down delay and holds memory
+// scale-up for ScaleUpDelay afterwards.
+func (rb *ResourceBurner) checkMemoryGuard(now time.Time) {
+	total, available, err := readMemInfo()
+	if err != nil {
+		log.Printf("Memory guard cannot read /proc/meminfo: %v", err)
+		return
+	}
+
+	// PSI needs CONFIG_PSI; without it the guard only watches MemAvailable
+	var stallPercent float64
+	var stallKnown bool
+	if p, err := readPressure("memory"); err != nil {
+		rb.pressureWarning.Do(func() {
+			log.Printf("⚠️  Memory guard cannot read PSI, watching MemAvailable only: %v", err)
+		})
+	} else {
+		stallPercent, stallKnown = rb.memoryStall.update(p.Some.Total, now)
+	}
+
+	rb.memoryMutex.Lock()
+	defer rb.memoryMutex.Unlock()
+
+	config := rb.currentConfig()
+	currentMB := rb.memorySizeMBLocked()
+	release, reason := memoryGuardRelease(currentMB, available/1024/1024, total/1024/1024, stallPercent, stallKnown, config)
+	if release <= 0 {
+		return
+	}
+
+	rb.resizeMemoryLocked(currentMB - release)
+	rb.memoryGuardHold = now.Add(config.ScaleUpDelay)
+	log.Printf("🚨 Memory guard released %d MB, holding %d MB: %s", release, currentMB-release, reason)
+}
+
+// memoryHeldLocked reports whether memory scale-up is on hold, either because
+// the burn cgroup hit memory.high or because the guard just released memory
+func (rb *ResourceBurner) memoryHeldLocked() bool {
+	return rb.memoryLimited || time.Now().Before(rb.memoryGuardHold)
+}
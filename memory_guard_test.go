@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryGuardRelease(t *testing.T) {
+	config := Config{MemoryPressureThreshold: 10, MinMemAvailablePercent: 10}
+
+	tests := []struct {
+		name        string
+		balloonMB   int64
+		availableMB int64
+		stall       float64
+		stallKnown  bool
+		want        int64
+	}{
+		{"healthy", 400, 2000, 1, true, 0},
+		{"empty balloon", 0, 100, 50, true, 0},
+		{"low available", 400, 900, 0, true, 100 + 100},
+		{"stalls", 400, 2000, 15, true, 200},
+		{"stalls unknown", 400, 2000, 15, false, 0},
+		{"both", 400, 950, 40, true, 200},
+		{"capped at balloon", 100, 100, 0, false, 100},
+		{"one MB left", 1, 2000, 20, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, reason := memoryGuardRelease(tt.balloonMB, tt.availableMB, 10000, tt.stall, tt.stallKnown, config)
+			if release != tt.want {
+				t.Errorf("release = %d MB, want %d MB (%s)", release, tt.want, reason)
+			}
+			if release > 0 && reason == "" {
+				t.Error("Expected a reason for the release")
+			}
+		})
+	}
+
+	// A zero threshold disables the PSI check
+	config.MemoryPressureThreshold = 0
+	if release, _ := memoryGuardRelease(400, 2000, 10000, 90, true, config); release != 0 {
+		t.Errorf("release with PSI disabled = %d, want 0", release)
+	}
+}
+
+func TestResourceBurner_CheckMemoryGuard(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.MaxMemoryMB = 16
+	rb.config.MinMemAvailablePercent = 10
+	rb.config.MemoryPressureThreshold = 10
+	rb.config.ScaleUpDelay = time.Minute
+	defer rb.releaseMemoryLoad()
+
+	rb.setMemoryMB(16)
+	start := time.Now()
+
+	// Plenty of MemAvailable and no stalls: nothing to do
+	writeProcFixture(t, map[string]string{
+		"meminfo":         "MemTotal: 1048576 kB\nMemAvailable: 524288 kB\n",
+		"pressure/memory": "some avg10=0.00 avg60=0.00 avg300=0.00 total=1000\n",
+	})
+	rb.checkMemoryGuard(start)
+	if rb.memorySizeMB() != 16 {
+		t.Fatalf("memorySizeMB() = %d, want 16 while healthy", rb.memorySizeMB())
+	}
+
+	// 50% of the last second stalled on memory halves the balloon at once
+	writeProcFixture(t, map[string]string{
+		"meminfo":         "MemTotal: 1048576 kB\nMemAvailable: 524288 kB\n",
+		"pressure/memory": "some avg10=0.00 avg60=0.00 avg300=0.00 total=501000\n",
+	})
+	rb.checkMemoryGuard(start.Add(time.Second))
+	if rb.memorySizeMB() != 8 {
+		t.Errorf("memorySizeMB() = %d, want 8 after a memory stall", rb.memorySizeMB())
+	}
+
+	// Scale-up stays on hold, the cooldown does not apply to the release
+	if size := rb.setMemoryMB(16); size != 8 {
+		t.Errorf("setMemoryMB(16) right after the guard = %d, want held at 8", size)
+	}
+	rb.memoryGuardHold = time.Time{}
+	if size := rb.setMemoryMB(16); size != 16 {
+		t.Errorf("setMemoryMB(16) after the hold = %d, want 16", size)
+	}
+}
+
+func TestResourceBurner_CheckMemoryGuardWithoutPSI(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.MaxMemoryMB = 8
+	rb.config.MinMemAvailablePercent = 10
+	defer rb.releaseMemoryLoad()
+
+	rb.setMemoryMB(8)
+
+	// 50 MB available of 1 GB is below the 10% floor
+	writeProcFixture(t, map[string]string{"meminfo": "MemTotal: 1048576 kB\nMemAvailable: 51200 kB\n"})
+	rb.checkMemoryGuard(time.Now())
+	if rb.memorySizeMB() != 0 {
+		t.Errorf("memorySizeMB() = %d, want the whole balloon released", rb.memorySizeMB())
+	}
+}
+
+func TestResourceBurner_AdjustMemoryLoadHeldByGuard(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.memoryGuardHold = time.Now().Add(time.Minute)
+
+	rb.adjustMemoryLoad(90.0, 10.0)
+	if rb.memorySizeMB() != 0 {
+		t.Errorf("adjustMemoryLoad() grew memory to %d MB during the guard hold", rb.memorySizeMB())
+	}
+}
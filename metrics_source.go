@@ -0,0 +1,451 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Supported entries for METRICS_SOURCES
+const (
+	MetricsSourceMetricsServer = "metrics-server"
+	MetricsSourceKubelet       = "kubelet"
+	MetricsSourceProcfs        = "procfs"
+)
+
+// procRoot is where procfs is mounted; tests point it at a fixture directory
+var procRoot = "/proc"
+
+// procfsSampleWindow is how long the procfs source measures CPU time for each reading
+const procfsSampleWindow = 500 * time.Millisecond
+
+// NodeUsage is a single utilization reading for the node goburn runs on
+type NodeUsage struct {
+	CPUPercent     float64
+	MemoryPercent  float64
+	CPUCapacity    int64 // millicores
+	MemoryCapacity int64 // bytes
+	Timestamp      time.Time
+	Window         time.Duration
+}
+
+// MetricsSource reports node level CPU and memory utilization
+type MetricsSource interface {
+	Name() string
+	NodeUsage(ctx context.Context) (NodeUsage, error)
+}
+
+func newMetricsSource(name string, config Config, k8sClient kubernetes.Interface, metricsClient metricsclientset.Interface) (MetricsSource, error) {
+	switch name {
+	case MetricsSourceMetricsServer:
+		return newMetricsServerSource(k8sClient, metricsClient, config.NodeName), nil
+	case MetricsSourceKubelet:
+		return newKubeletSource(k8sClient, config.NodeName), nil
+	case MetricsSourceProcfs:
+		return newProcfsSource(procfsSampleWindow), nil
+	default:
+		return nil, fmt.Errorf("unknown metrics source %q", name)
+	}
+}
+
+// newMetricsChain builds the prioritized chain of sources listed in config.MetricsSources
+func newMetricsChain(config Config, k8sClient kubernetes.Interface, metricsClient metricsclientset.Interface) (*metricsChain, error) {
+	if len(config.MetricsSources) == 0 {
+		return nil, fmt.Errorf("no metrics sources configured")
+	}
+
+	sources := make([]MetricsSource, 0, len(config.MetricsSources))
+	for _, name := range config.MetricsSources {
+		source, err := newMetricsSource(name, config, k8sClient, metricsClient)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return &metricsChain{sources: sources, maxAge: config.MetricsMaxAge}, nil
+}
+
+func procPath(elem ...string) string {
+	return filepath.Join(append([]string{procRoot}, elem...)...)
+}
+
+// metricsServerSource reads node usage from the metrics.k8s.io API
+type metricsServerSource struct {
+	k8sClient     kubernetes.Interface
+	metricsClient metricsclientset.Interface
+	nodeName      string
+}
+
+func newMetricsServerSource(k8sClient kubernetes.Interface, metricsClient metricsclientset.Interface, nodeName string) *metricsServerSource {
+	return &metricsServerSource{
+		k8sClient:     k8sClient,
+		metricsClient: metricsClient,
+		nodeName:      nodeName,
+	}
+}
+
+func (s *metricsServerSource) Name() string {
+	return MetricsSourceMetricsServer
+}
+
+func (s *metricsServerSource) nodeMetrics(ctx context.Context) (*metricsv1beta1.NodeMetrics, error) {
+	nodeMetrics, err := s.metricsClient.MetricsV1beta1().NodeMetricses().Get(ctx, s.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node metrics: %v", err)
+	}
+	return nodeMetrics, nil
+}
+
+func (s *metricsServerSource) NodeUsage(ctx context.Context) (NodeUsage, error) {
+	nodeMetrics, err := s.nodeMetrics(ctx)
+	if err != nil {
+		return NodeUsage{}, err
+	}
+
+	// Get node capacity
+	node, err := s.k8sClient.CoreV1().Nodes().Get(ctx, s.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return NodeUsage{}, fmt.Errorf("failed to get node info: %v", err)
+	}
+
+	cpuCapacity := node.Status.Capacity.Cpu().MilliValue()
+	memoryCapacity := node.Status.Capacity.Memory().Value()
+	if cpuCapacity == 0 || memoryCapacity == 0 {
+		return NodeUsage{}, fmt.Errorf("node %s reports no CPU or memory capacity", s.nodeName)
+	}
+
+	cpuUsage := nodeMetrics.Usage.Cpu().MilliValue()
+	memoryUsage := nodeMetrics.Usage.Memory().Value()
+
+	return NodeUsage{
+		CPUPercent:     float64(cpuUsage) / float64(cpuCapacity) * 100,
+		MemoryPercent:  float64(memoryUsage) / float64(memoryCapacity) * 100,
+		CPUCapacity:    cpuCapacity,
+		MemoryCapacity: memoryCapacity,
+		Timestamp:      nodeMetrics.Timestamp.Time,
+		Window:         nodeMetrics.Window.Duration,
+	}, nil
+}
+
+// kubeletSummary is the subset of the kubelet /stats/summary response goburn needs
+type kubeletSummary struct {
+	Node struct {
+		CPU struct {
+			Time           metav1.Time `json:"time"`
+			UsageNanoCores *uint64     `json:"usageNanoCores"`
+		} `json:"cpu"`
+		Memory struct {
+			WorkingSetBytes *uint64 `json:"workingSetBytes"`
+		} `json:"memory"`
+	} `json:"node"`
+}
+
+// kubeletSource reads node usage from the kubelet summary API through the API server node proxy
+type kubeletSource struct {
+	k8sClient kubernetes.Interface
+	nodeName  string
+}
+
+func newKubeletSource(k8sClient kubernetes.Interface, nodeName string) *kubeletSource {
+	return &kubeletSource{
+		k8sClient: k8sClient,
+		nodeName:  nodeName,
+	}
+}
+
+func (s *kubeletSource) Name() string {
+	return MetricsSourceKubelet
+}
+
+func (s *kubeletSource) NodeUsage(ctx context.Context) (NodeUsage, error) {
+	data, err := s.k8sClient.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", s.nodeName, "proxy", "stats", "summary").
+		DoRaw(ctx)
+	if err != nil {
+		return NodeUsage{}, fmt.Errorf("failed to get kubelet summary: %v", err)
+	}
+
+	node, err := s.k8sClient.CoreV1().Nodes().Get(ctx, s.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return NodeUsage{}, fmt.Errorf("failed to get node info: %v", err)
+	}
+
+	return parseKubeletSummary(data, node.Status.Capacity.Cpu().MilliValue(), node.Status.Capacity.Memory().Value())
+}
+
+func parseKubeletSummary(data []byte, cpuCapacity, memoryCapacity int64) (NodeUsage, error) {
+	var summary kubeletSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return NodeUsage{}, fmt.Errorf("failed to decode kubelet summary: %v", err)
+	}
+	if summary.Node.CPU.UsageNanoCores == nil || summary.Node.Memory.WorkingSetBytes == nil {
+		return NodeUsage{}, fmt.Errorf("kubelet summary has no node CPU or memory usage")
+	}
+	if cpuCapacity == 0 || memoryCapacity == 0 {
+		return NodeUsage{}, fmt.Errorf("node reports no CPU or memory capacity")
+	}
+
+	cpuUsage := float64(*summary.Node.CPU.UsageNanoCores) / 1e6 // millicores
+	memoryUsage := float64(*summary.Node.Memory.WorkingSetBytes)
+
+	return NodeUsage{
+		CPUPercent:     cpuUsage / float64(cpuCapacity) * 100,
+		MemoryPercent:  memoryUsage / float64(memoryCapacity) * 100,
+		CPUCapacity:    cpuCapacity,
+		MemoryCapacity: memoryCapacity,
+		Timestamp:      summary.Node.CPU.Time.Time,
+	}, nil
+}
+
+// procfsSource reads node usage straight from /proc/stat and /proc/meminfo.
+// It works without metrics-server and measures CPU over a short window.
+type procfsSource struct {
+	window time.Duration
+	mu     sync.Mutex
+}
+
+func newProcfsSource(window time.Duration) *procfsSource {
+	return &procfsSource{window: window}
+}
+
+func (s *procfsSource) Name() string {
+	return MetricsSourceProcfs
+}
+
+func (s *procfsSource) NodeUsage(ctx context.Context) (NodeUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before, err := readCPUTimes()
+	if err != nil {
+		return NodeUsage{}, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return NodeUsage{}, ctx.Err()
+	case <-time.After(s.window):
+	}
+
+	after, err := readCPUTimes()
+	if err != nil {
+		return NodeUsage{}, err
+	}
+
+	memTotal, memAvailable, err := readMemInfo()
+	if err != nil {
+		return NodeUsage{}, err
+	}
+
+	return NodeUsage{
+		CPUPercent:     cpuBusyPercent(before, after),
+		MemoryPercent:  float64(memTotal-memAvailable) / float64(memTotal) * 100,
+		CPUCapacity:    int64(after.cpus) * 1000,
+		MemoryCapacity: memTotal,
+		Timestamp:      time.Now(),
+		Window:         s.window,
+	}, nil
+}
+
+// cpuTimes holds the aggregate jiffy counters from the "cpu" line of /proc/stat
+type cpuTimes struct {
+	total uint64
+	idle  uint64
+	cpus  int
+}
+
+func readCPUTimes() (cpuTimes, error) {
+	file, err := os.Open(procPath("stat"))
+	if err != nil {
+		return cpuTimes{}, fmt.Errorf("failed to open /proc/stat: %v", err)
+	}
+	defer file.Close()
+
+	var times cpuTimes
+	found := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], "cpu") {
+			continue
+		}
+		if fields[0] != "cpu" {
+			times.cpus++
+			continue
+		}
+		if len(fields) < 5 {
+			return cpuTimes{}, fmt.Errorf("malformed cpu line in /proc/stat")
+		}
+		// user nice system idle iowait irq softirq steal; guest time is
+		// already accounted for in user and nice
+		for i, field := range fields[1:] {
+			if i >= 8 {
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return cpuTimes{}, fmt.Errorf("malformed cpu line in /proc/stat: %v", err)
+			}
+			times.total += value
+			if i == 3 || i == 4 {
+				times.idle += value
+			}
+		}
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, fmt.Errorf("failed to read /proc/stat: %v", err)
+	}
+	if !found || times.cpus == 0 {
+		return cpuTimes{}, fmt.Errorf("no cpu counters found in /proc/stat")
+	}
+	return times, nil
+}
+
+func cpuBusyPercent(before, after cpuTimes) float64 {
+	if after.total <= before.total {
+		return 0
+	}
+	total := float64(after.total - before.total)
+	idle := float64(after.idle - before.idle)
+	if idle > total {
+		return 0
+	}
+	return (total - idle) / total * 100
+}
+
+// readMemInfo returns MemTotal and MemAvailable in bytes
+func readMemInfo() (total, available int64, err error) {
+	values, err := readMemInfoFields("MemTotal", "MemAvailable")
+	if err != nil {
+		return 0, 0, err
+	}
+	total, available = values["MemTotal"], values["MemAvailable"]
+	if total == 0 {
+		return 0, 0, fmt.Errorf("MemTotal missing from /proc/meminfo")
+	}
+	return total, available, nil
+}
+
+func readMemInfoFields(keys ...string) (map[string]int64, error) {
+	file, err := os.Open(procPath("meminfo"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /proc/meminfo: %v", err)
+	}
+	defer file.Close()
+
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+
+	values := make(map[string]int64, len(keys))
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		key := strings.TrimSuffix(fields[0], ":")
+		if !wanted[key] {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed %s in /proc/meminfo: %v", key, err)
+		}
+		if len(fields) >= 3 && fields[2] == "kB" {
+			value *= 1024
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read /proc/meminfo: %v", err)
+	}
+	return values, nil
+}
+
+// metricsChain tries each source in priority order and returns the first
+// fresh reading. A source is skipped when it errors or when its reading is
+// older than maxAge.
+type metricsChain struct {
+	sources []MetricsSource
+	maxAge  time.Duration
+
+	mu     sync.RWMutex
+	active string
+}
+
+// Name reports the source that produced the most recent reading
+func (c *metricsChain) Name() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.active == "" {
+		return "none"
+	}
+	return c.active
+}
+
+func (c *metricsChain) NodeUsage(ctx context.Context) (NodeUsage, error) {
+	var failures []string
+	for _, source := range c.sources {
+		usage, err := source.NodeUsage(ctx)
+		if err == nil {
+			err = c.checkFresh(usage, time.Now())
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", source.Name(), err))
+			continue
+		}
+
+		c.setActive(source.Name())
+		return usage, nil
+	}
+
+	c.setActive("")
+	return NodeUsage{}, fmt.Errorf("all metrics sources failed: %s", strings.Join(failures, "; "))
+}
+
+func (c *metricsChain) checkFresh(usage NodeUsage, now time.Time) error {
+	if usage.Timestamp.IsZero() {
+		return fmt.Errorf("reading has no timestamp")
+	}
+	if c.maxAge > 0 {
+		if age := now.Sub(usage.Timestamp); age > c.maxAge {
+			return fmt.Errorf("reading is stale (age %s, window %s, max age %s)",
+				age.Round(time.Second), usage.Window, c.maxAge)
+		}
+	}
+	return nil
+}
+
+func (c *metricsChain) setActive(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name == c.active {
+		return
+	}
+	if name == "" {
+		log.Printf("📡 No metrics source available (was %s)", c.active)
+	} else if c.active == "" {
+		log.Printf("📡 Using metrics source: %s", name)
+	} else {
+		log.Printf("📡 Metrics source switched from %s to %s", c.active, name)
+	}
+	c.active = name
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// writeProcFixture points procRoot at a temporary directory holding the given files
+func writeProcFixture(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create fixture dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write fixture %s: %v", name, err)
+		}
+	}
+
+	oldRoot := procRoot
+	procRoot = dir
+	t.Cleanup(func() { procRoot = oldRoot })
+}
+
+const testProcStat = `cpu  100 0 100 700 100 0 0 0 0 0
+cpu0 50 0 50 350 50 0 0 0 0 0
+cpu1 50 0 50 350 50 0 0 0 0 0
+intr 12345
+ctxt 6789
+`
+
+const testMemInfo = `MemTotal:        4000000 kB
+MemFree:          500000 kB
+MemAvailable:    1000000 kB
+Buffers:           10000 kB
+`
+
+func TestReadCPUTimes(t *testing.T) {
+	writeProcFixture(t, map[string]string{"stat": testProcStat})
+
+	times, err := readCPUTimes()
+	if err != nil {
+		t.Fatalf("readCPUTimes() error = %v", err)
+	}
+	if times.total != 1000 {
+		t.Errorf("total = %d, want 1000", times.total)
+	}
+	if times.idle != 800 {
+		t.Errorf("idle = %d, want 800", times.idle)
+	}
+	if times.cpus != 2 {
+		t.Errorf("cpus = %d, want 2", times.cpus)
+	}
+}
+
+func TestCPUBusyPercent(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after cpuTimes
+		expected      float64
+	}{
+		{"half busy", cpuTimes{total: 1000, idle: 800}, cpuTimes{total: 1200, idle: 900}, 50},
+		{"fully idle", cpuTimes{total: 1000, idle: 800}, cpuTimes{total: 1100, idle: 900}, 0},
+		{"no progress", cpuTimes{total: 1000, idle: 800}, cpuTimes{total: 1000, idle: 800}, 0},
+		{"counter reset", cpuTimes{total: 1000, idle: 800}, cpuTimes{total: 10, idle: 5}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := cpuBusyPercent(tt.before, tt.after)
+			if result != tt.expected {
+				t.Errorf("cpuBusyPercent() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestReadMemInfo(t *testing.T) {
+	writeProcFixture(t, map[string]string{"meminfo": testMemInfo})
+
+	total, available, err := readMemInfo()
+	if err != nil {
+		t.Fatalf("readMemInfo() error = %v", err)
+	}
+	if total != 4000000*1024 {
+		t.Errorf("total = %d, want %d", total, 4000000*1024)
+	}
+	if available != 1000000*1024 {
+		t.Errorf("available = %d, want %d", available, 1000000*1024)
+	}
+}
+
+func TestProcfsSource_NodeUsage(t *testing.T) {
+	writeProcFixture(t, map[string]string{
+		"stat":    testProcStat,
+		"meminfo": testMemInfo,
+	})
+
+	source := newProcfsSource(time.Millisecond)
+	usage, err := source.NodeUsage(context.Background())
+	if err != nil {
+		t.Fatalf("NodeUsage() error = %v", err)
+	}
+
+	// The fixture does not change between reads so the CPU looks idle
+	if usage.CPUPercent != 0 {
+		t.Errorf("CPUPercent = %v, want 0", usage.CPUPercent)
+	}
+	if usage.MemoryPercent != 75 {
+		t.Errorf("MemoryPercent = %v, want 75", usage.MemoryPercent)
+	}
+	if usage.CPUCapacity != 2000 {
+		t.Errorf("CPUCapacity = %v, want 2000", usage.CPUCapacity)
+	}
+	if usage.Window != time.Millisecond {
+		t.Errorf("Window = %v, want %v", usage.Window, time.Millisecond)
+	}
+}
+
+func TestProcfsSource_MissingFiles(t *testing.T) {
+	writeProcFixture(t, map[string]string{})
+
+	source := newProcfsSource(time.Millisecond)
+	if _, err := source.NodeUsage(context.Background()); err == nil {
+		t.Error("Expected error when /proc/stat is missing, got nil")
+	}
+}
+
+func TestMetricsServerSource_NodeUsage(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-node"},
+		Status: corev1.NodeStatus{
+			Capacity: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("4"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	k8sClient := fake.NewSimpleClientset(node)
+
+	source := newMetricsServerSource(k8sClient, metricsfake.NewSimpleClientset(), "test-node")
+	if source.Name() != MetricsSourceMetricsServer {
+		t.Errorf("Name() = %s, want %s", source.Name(), MetricsSourceMetricsServer)
+	}
+
+	// The fake metrics client never has node metrics, so the source must fail cleanly
+	if _, err := source.NodeUsage(context.Background()); err == nil {
+		t.Error("Expected error without node metrics, got nil")
+	}
+}
+
+func TestNewMetricsSource(t *testing.T) {
+	config := Config{NodeName: "test-node"}
+	k8sClient := fake.NewSimpleClientset()
+	metricsClient := metricsfake.NewSimpleClientset()
+
+	for _, name := range []string{MetricsSourceMetricsServer, MetricsSourceProcfs} {
+		source, err := newMetricsSource(name, config, k8sClient, metricsClient)
+		if err != nil {
+			t.Fatalf("newMetricsSource(%s) error = %v", name, err)
+		}
+		if source.Name() != name {
+			t.Errorf("newMetricsSource(%s).Name() = %s", name, source.Name())
+		}
+	}
+
+	if _, err := newMetricsSource("bogus", config, k8sClient, metricsClient); err == nil {
+		t.Error("Expected error for unknown metrics source, got nil")
+	}
+}
+
+func TestParseKubeletSummary(t *testing.T) {
+	data := []byte(`{"node":{"nodeName":"test-node",
+		"cpu":{"time":"2024-01-15T10:30:00Z","usageNanoCores":1000000000},
+		"memory":{"time":"2024-01-15T10:30:00Z","workingSetBytes":2147483648}}}`)
+
+	usage, err := parseKubeletSummary(data, 4000, 8*1024*1024*1024)
+	if err != nil {
+		t.Fatalf("parseKubeletSummary() error = %v", err)
+	}
+	if usage.CPUPercent != 25 {
+		t.Errorf("CPUPercent = %v, want 25", usage.CPUPercent)
+	}
+	if usage.MemoryPercent != 25 {
+		t.Errorf("MemoryPercent = %v, want 25", usage.MemoryPercent)
+	}
+	if !usage.Timestamp.Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("Timestamp = %v", usage.Timestamp)
+	}
+
+	if _, err := parseKubeletSummary([]byte(`{"node":{}}`), 4000, 1024); err == nil {
+		t.Error("Expected error for summary without usage, got nil")
+	}
+	if _, err := parseKubeletSummary([]byte(`not json`), 4000, 1024); err == nil {
+		t.Error("Expected error for malformed summary, got nil")
+	}
+}
+
+// fakeMetricsSource returns a canned reading or error
+type fakeMetricsSource struct {
+	name  string
+	usage NodeUsage
+	err   error
+}
+
+func (f *fakeMetricsSource) Name() string {
+	return f.name
+}
+
+func (f *fakeMetricsSource) NodeUsage(ctx context.Context) (NodeUsage, error) {
+	return f.usage, f.err
+}
+
+func TestMetricsChain_Fallback(t *testing.T) {
+	now := time.Now()
+	failing := &fakeMetricsSource{name: "failing", err: errors.New("unavailable")}
+	stale := &fakeMetricsSource{name: "stale", usage: NodeUsage{CPUPercent: 10, Timestamp: now.Add(-10 * time.Minute), Window: time.Minute}}
+	fresh := &fakeMetricsSource{name: "fresh", usage: NodeUsage{CPUPercent: 42, Timestamp: now}}
+
+	chain := &metricsChain{sources: []MetricsSource{failing, stale, fresh}, maxAge: 2 * time.Minute}
+	if chain.Name() != "none" {
+		t.Errorf("Name() before first reading = %s, want none", chain.Name())
+	}
+
+	usage, err := chain.NodeUsage(context.Background())
+	if err != nil {
+		t.Fatalf("NodeUsage() error = %v", err)
+	}
+	if usage.CPUPercent != 42 {
+		t.Errorf("CPUPercent = %v, want 42 from the fresh source", usage.CPUPercent)
+	}
+	if chain.Name() != "fresh" {
+		t.Errorf("Name() = %s, want fresh", chain.Name())
+	}
+
+	// Once the primary recovers it takes over again
+	failing.err = nil
+	failing.usage = NodeUsage{CPUPercent: 7, Timestamp: time.Now()}
+	usage, err = chain.NodeUsage(context.Background())
+	if err != nil {
+		t.Fatalf("NodeUsage() error = %v", err)
+	}
+	if usage.CPUPercent != 7 || chain.Name() != "failing" {
+		t.Errorf("Expected primary source to be active again, got %s with %v", chain.Name(), usage.CPUPercent)
+	}
+}
+
+func TestMetricsChain_AllFail(t *testing.T) {
+	chain := &metricsChain{
+		sources: []MetricsSource{
+			&fakeMetricsSource{name: "a", err: errors.New("boom")},
+			&fakeMetricsSource{name: "b", usage: NodeUsage{}},
+		},
+		maxAge: time.Minute,
+	}
+
+	_, err := chain.NodeUsage(context.Background())
+	if err == nil {
+		t.Fatal("Expected error when every source fails, got nil")
+	}
+	if !strings.Contains(err.Error(), "a: boom") || !strings.Contains(err.Error(), "b: reading has no timestamp") {
+		t.Errorf("Error should name every failing source, got %v", err)
+	}
+	if chain.Name() != "none" {
+		t.Errorf("Name() = %s, want none", chain.Name())
+	}
+}
+
+func TestNewMetricsChain(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+	metricsClient := metricsfake.NewSimpleClientset()
+
+	config := Config{
+		NodeName:       "test-node",
+		MetricsSources: []string{MetricsSourceMetricsServer, MetricsSourceKubelet, MetricsSourceProcfs},
+		MetricsMaxAge:  time.Minute,
+	}
+	chain, err := newMetricsChain(config, k8sClient, metricsClient)
+	if err != nil {
+		t.Fatalf("newMetricsChain() error = %v", err)
+	}
+	if len(chain.sources) != 3 {
+		t.Errorf("Expected 3 sources, got %d", len(chain.sources))
+	}
+
+	config.MetricsSources = []string{MetricsSourceProcfs, "bogus"}
+	if _, err := newMetricsChain(config, k8sClient, metricsClient); err == nil {
+		t.Error("Expected error for unknown source in chain, got nil")
+	}
+
+	config.MetricsSources = nil
+	if _, err := newMetricsChain(config, k8sClient, metricsClient); err == nil {
+		t.Error("Expected error for empty chain, got nil")
+	}
+}
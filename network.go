@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Supported values for NETWORK_MODE
+const (
+	NetworkModeUDP      = "udp"
+	NetworkModeTCP      = "tcp"
+	NetworkModeLoopback = "loopback"
+)
+
+// udpPayloadBytes keeps datagrams under a typical 1500 byte MTU
+const udpPayloadBytes = 1400
+
+// tcpWriteBytes is the size of each paced write on stream connections
+const tcpWriteBytes = 32 * 1024
+
+// networkRetryDelay is how long a stream waits before reconnecting after an error
+const networkRetryDelay = time.Second
+
+// networkWriteTimeout bounds a single write, so a peer that stops reading
+// makes the stream reconnect instead of blocking it
+const networkWriteTimeout = 5 * time.Second
+
+// NetworkRate is the throughput measured on the monitored interface
+type NetworkRate struct {
+	RxBitsPerSec float64
+	TxBitsPerSec float64
+}
+
+// Mbps returns the combined receive and transmit rate in megabits per second
+func (r NetworkRate) Mbps() float64 {
+	return (r.RxBitsPerSec + r.TxBitsPerSec) / 1e6
+}
+
+// netCounters are the cumulative byte counters of one interface
+type netCounters struct {
+	rxBytes uint64
+	txBytes uint64
+}
+
+// networkRateTracker turns cumulative interface counters into rates by
+// remembering the previous snapshot
+type networkRateTracker struct {
+	mu       sync.Mutex
+	prev     netCounters
+	prevTime time.Time
+	valid    bool
+}
+
+// update records a new snapshot and returns the rate since the previous one.
+// ok is false when there is no usable previous snapshot, either because this
+// is the first sample or because the counters were reset.
+func (t *networkRateTracker) update(counters netCounters, now time.Time) (rate NetworkRate, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev, prevTime, valid := t.prev, t.prevTime, t.valid
+	t.prev, t.prevTime, t.valid = counters, now, true
+
+	elapsed := now.Sub(prevTime).Seconds()
+	if !valid || elapsed <= 0 {
+		return NetworkRate{}, false
+	}
+
+	rxDelta, rxOK := counterDelta(prev.rxBytes, counters.rxBytes)
+	txDelta, txOK := counterDelta(prev.txBytes, counters.txBytes)
+	if !rxOK || !txOK {
+		return NetworkRate{}, false
+	}
+
+	return NetworkRate{
+		RxBitsPerSec: float64(rxDelta) * 8 / elapsed,
+		TxBitsPerSec: float64(txDelta) * 8 / elapsed,
+	}, true
+}
+
+// counterDelta returns how far a counter advanced. A counter that went
+// backwards from the upper half of the 32-bit range wrapped around; any
+// other decrease is a reset, e.g. after an interface flap. It is not known
+// when a reset happened, so it reports no delta and the sample is skipped.
+func counterDelta(prev, cur uint64) (delta uint64, ok bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+	if prev > math.MaxUint32/2 && prev <= math.MaxUint32 && cur <= math.MaxUint32 {
+		return math.MaxUint32 - prev + cur + 1, true
+	}
+	return 0, false
+}
+
+// readInterfaceCounters reads the rx and tx byte counters of iface from /proc/net/dev
+func readInterfaceCounters(iface string) (netCounters, error) {
+	file, err := os.Open(procPath("net", "dev"))
+	if err != nil {
+		return netCounters{}, fmt.Errorf("failed to open /proc/net/dev: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, stats, found := strings.Cut(scanner.Text(), ":")
+		if !found || strings.TrimSpace(name) != iface {
+			continue
+		}
+
+		fields := strings.Fields(stats)
+		if len(fields) < 9 {
+			return netCounters{}, fmt.Errorf("malformed /proc/net/dev entry for %s", iface)
+		}
+		rxBytes, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return netCounters{}, fmt.Errorf("malformed rx bytes for %s: %v", iface, err)
+		}
+		txBytes, err := strconv.ParseUint(fields[8], 10, 64)
+		if err != nil {
+			return netCounters{}, fmt.Errorf("malformed tx bytes for %s: %v", iface, err)
+		}
+		return netCounters{rxBytes: rxBytes, txBytes: txBytes}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return netCounters{}, fmt.Errorf("failed to read /proc/net/dev: %v", err)
+	}
+	return netCounters{}, fmt.Errorf("interface %s not found in /proc/net/dev", iface)
+}
+
+// getNetworkUtilization returns the rx and tx rate of the configured interface
+// since the previous call
+func (rb *ResourceBurner) getNetworkUtilization() (NetworkRate, error) {
+	counters, err := readInterfaceCounters(rb.config.NetworkInterface)
+	if err != nil {
+		return NetworkRate{}, err
+	}
+
+	rate, ok := rb.networkTracker.update(counters, time.Now())
+	if !ok {
+		return NetworkRate{}, fmt.Errorf("no previous counters for %s yet (first sample or counter reset)", rb.config.NetworkInterface)
+	}
+	return rate, nil
+}
+
+// trafficGenerator sends traffic at a target rate over persistent
+// connections, pacing every write with a shared token bucket
+type trafficGenerator struct {
+	mode    string
+	iface   string
+	streams int
+	limiter *rate.Limiter
+
+	mu       sync.Mutex
+	targets  []string
+	rateMbps float64
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+func newTrafficGenerator(config Config) *trafficGenerator {
+	streams := config.NetworkStreams
+	if streams < 1 {
+		streams = 1
+	}
+
+	g := &trafficGenerator{
+		mode:    config.NetworkMode,
+		iface:   config.NetworkInterface,
+		streams: streams,
+		limiter: rate.NewLimiter(0, writeSize(config.NetworkMode)),
+	}
+	if config.NetworkTarget != "" {
+		g.targets = []string{config.NetworkTarget}
+	}
+	return g
+}
+
+// writeSize is the payload of a single write; UDP datagrams stay under a typical MTU
+func writeSize(mode string) int {
+	if mode == NetworkModeUDP {
+		return udpPayloadBytes
+	}
+	return tcpWriteBytes
+}
+
+// Rate returns the current target rate in Mbps
+func (g *trafficGenerator) Rate() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rateMbps
+}
+
+// SetRate changes the target rate; streams are started or stopped as the rate
+// moves to and from zero, everything else is just a token bucket update
+func (g *trafficGenerator) SetRate(mbps float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if mbps < 0 {
+		mbps = 0
+	}
+	g.rateMbps = mbps
+	g.limiter.SetLimit(rate.Limit(mbps * 1e6 / 8))
+
+	running := g.cancel != nil
+	if mbps > 0 && !running {
+		g.startLocked()
+	} else if mbps == 0 && running {
+		g.stopLocked()
+	}
+}
+
+// SetTargets replaces the peers traffic is sent to; running streams are
+// restarted only when the set actually changes
+func (g *trafficGenerator) SetTargets(targets []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if equalStrings(g.targets, targets) {
+		return
+	}
+	g.targets = append([]string(nil), targets...)
+
+	if g.rateMbps > 0 {
+		g.stopLocked()
+		g.startLocked()
+	}
+}
+
+// Targets returns the peers traffic is sent to
+func (g *trafficGenerator) Targets() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.targets...)
+}
+
+// Stop closes every connection and waits for the streams to exit
+func (g *trafficGenerator) Stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.rateMbps = 0
+	g.limiter.SetLimit(0)
+	g.stopLocked()
+}
+
+func (g *trafficGenerator) startLocked() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	targets := g.targets
+	if g.mode == NetworkModeLoopback {
+		sink, err := startLoopbackSink()
+		if err != nil {
+			log.Printf("Failed to start loopback traffic sink: %v", err)
+			cancel()
+			return
+		}
+		targets = []string{sink.Addr().String()}
+		go func() {
+			<-ctx.Done()
+			sink.Close()
+		}()
+	}
+	if len(targets) == 0 {
+		cancel()
+		return
+	}
+
+	g.cancel = cancel
+	for _, target := range targets {
+		for i := 0; i < g.streams; i++ {
+			g.wg.Add(1)
+			go g.stream(ctx, target)
+		}
+	}
+}
+
+func (g *trafficGenerator) stopLocked() {
+	if g.cancel == nil {
+		return
+	}
+	g.cancel()
+	g.cancel = nil
+	g.wg.Wait()
+}
+
+// stream keeps one connection to target open and writes to it as fast as
+// the token bucket allows, reconnecting after errors
+func (g *trafficGenerator) stream(ctx context.Context, target string) {
+	defer g.wg.Done()
+
+	payload := make([]byte, writeSize(g.mode))
+	rand.Read(payload)
+
+	for ctx.Err() == nil {
+		conn, err := g.dial(target)
+		if err != nil {
+			sleepContext(ctx, networkRetryDelay)
+			continue
+		}
+
+		// Stopping closes the connection, which unblocks a write in progress
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+			case <-done:
+			}
+		}()
+
+		for {
+			if err := g.limiter.WaitN(ctx, len(payload)); err != nil {
+				break
+			}
+			conn.SetWriteDeadline(time.Now().Add(networkWriteTimeout))
+			if _, err := conn.Write(payload); err != nil {
+				sleepContext(ctx, networkRetryDelay)
+				break
+			}
+		}
+		close(done)
+		conn.Close()
+	}
+}
+
+func (g *trafficGenerator) dial(target string) (net.Conn, error) {
+	if g.mode == NetworkModeLoopback {
+		return net.DialTimeout("tcp", target, 5*time.Second)
+	}
+	return dialFromInterface(g.mode, g.iface, target)
+}
+
+// startLoopbackSink accepts connections on 127.0.0.1 and discards what they send
+func startLoopbackSink() (net.Listener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return listener, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// dialFromInterface connects to target with the local address bound to iface,
+// so the traffic leaves through that interface instead of loopback
+func dialFromInterface(mode, iface, target string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network target %q: %v", target, err)
+	}
+	targetIPs, err := net.LookupIP(host)
+	if err != nil || len(targetIPs) == 0 {
+		return nil, fmt.Errorf("failed to resolve network target %q: %v", target, err)
+	}
+
+	localIP, err := interfaceAddr(iface, targetIPs[0].To4() == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	switch mode {
+	case NetworkModeUDP:
+		dialer.LocalAddr = &net.UDPAddr{IP: localIP}
+	case NetworkModeTCP:
+		dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+	default:
+		return nil, fmt.Errorf("unsupported network mode %q", mode)
+	}
+	return dialer.Dial(mode, net.JoinHostPort(targetIPs[0].String(), port))
+}
+
+// interfaceAddr returns the first address of iface in the requested family
+func interfaceAddr(iface string, ipv6 bool) (net.IP, error) {
+	ifi, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find interface %s: %v", iface, err)
+	}
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses of %s: %v", iface, err)
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if (ipNet.IP.To4() == nil) == ipv6 {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s has no usable address", iface)
+}
+
+// logNetworkMode reports where generated traffic goes and warns when it cannot count
+func (rb *ResourceBurner) logNetworkMode() {
+	switch rb.config.NetworkMode {
+	case NetworkModeLoopback:
+		log.Printf("⚠️  Network mode loopback: generated traffic does not cross %s and cannot satisfy the network minimum",
+			rb.config.NetworkInterface)
+	case NetworkModeUDP, NetworkModeTCP:
+		if rb.config.PeerLabelSelector != "" {
+			log.Printf("🌐 Network traffic: %s from %s to up to %d goburn peers matching %q on port %d",
+				rb.config.NetworkMode, rb.config.NetworkInterface, rb.config.MaxPeers, rb.config.PeerLabelSelector, rb.config.PeerPort)
+			return
+		}
+		if rb.config.NetworkTarget == "" {
+			log.Printf("⚠️  Network mode %s has no NETWORK_TARGET set: no traffic will be generated", rb.config.NetworkMode)
+			return
+		}
+		localIP, err := interfaceAddr(rb.config.NetworkInterface, false)
+		if err != nil {
+			log.Printf("⚠️  Network mode %s: %v", rb.config.NetworkMode, err)
+			return
+		}
+		log.Printf("🌐 Network traffic: %s from %s (%s) to %s",
+			rb.config.NetworkMode, rb.config.NetworkInterface, localIP, rb.config.NetworkTarget)
+	default:
+		log.Printf("⚠️  Unknown network mode %q: no traffic will be generated", rb.config.NetworkMode)
+	}
+}
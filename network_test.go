@@ -0,0 +1,271 @@
+package main
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+const testNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 1000000     100    0    0    0     0          0         0  1000000     100    0    0    0     0       0          0
+  eth0: 5000000    4000    0    0    0     0          0         0  2500000    3000    0    0    0     0       0          0
+veth0abc: 9999999    4000    0    0    0     0          0         0  9999999    3000    0    0    0     0       0          0
+`
+
+func TestReadInterfaceCounters(t *testing.T) {
+	writeProcFixture(t, map[string]string{"net/dev": testNetDev})
+
+	counters, err := readInterfaceCounters("eth0")
+	if err != nil {
+		t.Fatalf("readInterfaceCounters() error = %v", err)
+	}
+	if counters.rxBytes != 5000000 || counters.txBytes != 2500000 {
+		t.Errorf("counters = %+v, want rx 5000000 tx 2500000", counters)
+	}
+
+	// veth0abc must not be mistaken for eth0 and vice versa
+	counters, err = readInterfaceCounters("veth0abc")
+	if err != nil {
+		t.Fatalf("readInterfaceCounters() error = %v", err)
+	}
+	if counters.rxBytes != 9999999 {
+		t.Errorf("rxBytes = %d, want 9999999", counters.rxBytes)
+	}
+
+	if _, err := readInterfaceCounters("ens0"); err == nil {
+		t.Error("Expected error for missing interface, got nil")
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur uint64
+		expected  uint64
+		ok        bool
+	}{
+		{"increase", 100, 250, 150, true},
+		{"unchanged", 100, 100, 0, true},
+		{"32-bit wraparound", math.MaxUint32 - 9, 10, 20, true},
+		{"64-bit reset", 1 << 40, 500, 0, false},
+		{"reset below 4 GiB", 1 << 30, 500, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta, ok := counterDelta(tt.prev, tt.cur)
+			if delta != tt.expected || ok != tt.ok {
+				t.Errorf("counterDelta(%d, %d) = %d, %v, want %d, %v", tt.prev, tt.cur, delta, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNetworkRateTracker(t *testing.T) {
+	var tracker networkRateTracker
+	start := time.Now()
+
+	if _, ok := tracker.update(netCounters{rxBytes: 0, txBytes: 0}, start); ok {
+		t.Error("First sample should not produce a rate")
+	}
+
+	// 2.5 MB received and 1.25 MB sent over 2s is 10 Mbps rx and 5 Mbps tx
+	rate, ok := tracker.update(netCounters{rxBytes: 2500000, txBytes: 1250000}, start.Add(2*time.Second))
+	if !ok {
+		t.Fatal("Second sample should produce a rate")
+	}
+	if rate.RxBitsPerSec != 10e6 || rate.TxBitsPerSec != 5e6 {
+		t.Errorf("rate = %+v, want rx 10e6 tx 5e6", rate)
+	}
+	if rate.Mbps() != 15 {
+		t.Errorf("Mbps() = %v, want 15", rate.Mbps())
+	}
+
+	// A counter reset re-baselines instead of reporting a bogus rate
+	if _, ok := tracker.update(netCounters{rxBytes: 1 << 40, txBytes: 1 << 40}, start.Add(3*time.Second)); !ok {
+		t.Error("Large increase should still produce a rate")
+	}
+	if _, ok := tracker.update(netCounters{rxBytes: 10, txBytes: 10}, start.Add(4*time.Second)); ok {
+		t.Error("Counter reset should not produce a rate")
+	}
+	rate, ok = tracker.update(netCounters{rxBytes: 10 + 125000, txBytes: 10}, start.Add(5*time.Second))
+	if !ok || rate.RxBitsPerSec != 1e6 || rate.TxBitsPerSec != 0 {
+		t.Errorf("rate after reset = %+v, %v, want rx 1e6 tx 0", rate, ok)
+	}
+}
+
+func TestResourceBurner_GetNetworkUtilization(t *testing.T) {
+	writeProcFixture(t, map[string]string{"net/dev": testNetDev})
+	rb := createTestResourceBurner(t)
+
+	if _, err := rb.getNetworkUtilization(); err == nil {
+		t.Error("Expected error on first sample, got nil")
+	}
+
+	rate, err := rb.getNetworkUtilization()
+	if err != nil {
+		t.Fatalf("getNetworkUtilization() error = %v", err)
+	}
+	// The fixture does not change, so the interface is idle
+	if rate.Mbps() != 0 {
+		t.Errorf("Mbps() = %v, want 0", rate.Mbps())
+	}
+}
+
+func TestInterfaceAddr(t *testing.T) {
+	ip, err := interfaceAddr("lo", false)
+	if err != nil {
+		t.Skipf("loopback interface not available: %v", err)
+	}
+	if !ip.IsLoopback() {
+		t.Errorf("interfaceAddr(lo) = %v, want a loopback address", ip)
+	}
+
+	if _, err := interfaceAddr("does-not-exist0", false); err == nil {
+		t.Error("Expected error for missing interface, got nil")
+	}
+}
+
+func TestTrafficGenerator_UDPRate(t *testing.T) {
+	if _, err := interfaceAddr("lo", false); err != nil {
+		t.Skipf("loopback interface not available: %v", err)
+	}
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	g := newTrafficGenerator(Config{
+		NetworkMode:      NetworkModeUDP,
+		NetworkInterface: "lo",
+		NetworkTarget:    listener.LocalAddr().String(),
+		NetworkStreams:   2,
+	})
+	defer g.Stop()
+
+	// 8 Mbps is 1 MB/s
+	g.SetRate(8)
+	if g.Rate() != 8 {
+		t.Errorf("Rate() = %v, want 8", g.Rate())
+	}
+
+	var received int
+	buffer := make([]byte, 2048)
+	deadline := time.Now().Add(time.Second)
+	for {
+		listener.SetReadDeadline(deadline)
+		n, addr, err := listener.ReadFrom(buffer)
+		if err != nil {
+			break
+		}
+		if n != udpPayloadBytes {
+			t.Fatalf("datagram size = %d, want %d", n, udpPayloadBytes)
+		}
+		if !addr.(*net.UDPAddr).IP.IsLoopback() {
+			t.Fatalf("datagram came from %v, want the lo address", addr)
+		}
+		received += n
+	}
+
+	// Other tests leave CPU workers spinning, so only the pacing ceiling is
+	// checked strictly; a free-running sender would be far above it
+	if received == 0 || received > 1500*1000 {
+		t.Errorf("received %d bytes in 1s at 8 Mbps, want about 1000000", received)
+	}
+}
+
+func TestTrafficGenerator_SetRate(t *testing.T) {
+	g := newTrafficGenerator(Config{NetworkMode: NetworkModeLoopback, NetworkStreams: 1})
+
+	g.SetRate(50)
+	if g.cancel == nil {
+		t.Error("Expected streams to start when the rate becomes positive")
+	}
+
+	// Changing a non-zero rate only retunes the token bucket
+	g.SetRate(20)
+	if g.Rate() != 20 || g.limiter.Limit() != 20*1e6/8 {
+		t.Errorf("Rate() = %v, limit = %v, want 20 Mbps", g.Rate(), g.limiter.Limit())
+	}
+	if g.cancel == nil {
+		t.Error("Expected streams to keep running")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		g.SetRate(0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SetRate(0) did not stop the streams")
+	}
+	if g.cancel != nil {
+		t.Error("Expected streams to stop when the rate drops to zero")
+	}
+}
+
+func TestTrafficGenerator_StopsWithStalledPeer(t *testing.T) {
+	if _, err := interfaceAddr("lo", false); err != nil {
+		t.Skipf("loopback interface not available: %v", err)
+	}
+
+	// A peer that accepts but never reads fills the socket buffers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	g := newTrafficGenerator(Config{
+		NetworkMode:      NetworkModeTCP,
+		NetworkInterface: "lo",
+		NetworkTarget:    listener.Addr().String(),
+		NetworkStreams:   1,
+		MaxNetworkMbps:   1000,
+	})
+	g.SetRate(1000)
+	time.Sleep(500 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		g.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop() blocked on a peer that stopped reading")
+	}
+}
+
+func TestTrafficGenerator_NoTarget(t *testing.T) {
+	g := newTrafficGenerator(Config{NetworkMode: NetworkModeUDP})
+
+	// Without a target there is nothing to send to, but the rate is still tracked
+	g.SetRate(10)
+	if g.cancel != nil {
+		t.Error("Expected no streams without a target")
+	}
+	if g.Rate() != 10 {
+		t.Errorf("Rate() = %v, want 10", g.Rate())
+	}
+	g.Stop()
+	if g.Rate() != 0 {
+		t.Errorf("Rate() after Stop = %v, want 0", g.Rate())
+	}
+}
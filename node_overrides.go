@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeOverridePrefix marks the node labels and annotations goburn reads
+const nodeOverridePrefix = "goburn.io/"
+
+// nodeOverrideInterval is how often the node is read again for changed overrides
+const nodeOverrideInterval = 30 * time.Second
+
+// nodeOverrideSettings maps the goburn.io/ names to the settings they
+// override. They cover what differs between node pools; everything else
+// comes from the shared configuration.
+var nodeOverrideSettings = map[string]string{
+	"target-cpu":         "TARGET_CPU_UTILIZATION",
+	"target-memory":      "TARGET_MEMORY_UTILIZATION",
+	"min-cpu":            "MIN_CPU_UTILIZATION",
+	"min-memory":         "MIN_MEMORY_UTILIZATION",
+	"min-network-mbps":   "MIN_NETWORK_UTILIZATION_MBPS",
+	"enable-memory":      "ENABLE_MEMORY_UTILIZATION",
+	"max-memory-mb":      "MAX_MEMORY_MB",
+	"max-network-mbps":   "MAX_NETWORK_MBPS",
+	"cpu-strategy":       "CPU_STRATEGY",
+	"cpu-percentile":     "CPU_PERCENTILE",
+	"memory-percentile":  "MEMORY_PERCENTILE",
+	"network-percentile": "NETWORK_PERCENTILE",
+	"percentile-window":  "PERCENTILE_WINDOW",
+}
+
+// nodeOverrides returns the settings overridden by the goburn.io/ labels and
+// annotations of node, keyed by their environment variable names, or nil when
+// there are none. Annotations win over labels, as they are not limited to
+// label syntax. Names goburn does not know are returned separately.
+func nodeOverrides(node *corev1.Node) (settings map[string]string, unknown []string) {
+	for _, source := range []map[string]string{node.Labels, node.Annotations} {
+		for name, value := range source {
+			short, ok := strings.CutPrefix(name, nodeOverridePrefix)
+			if !ok {
+				continue
+			}
+			key, ok := nodeOverrideSettings[short]
+			if !ok {
+				unknown = append(unknown, name)
+				continue
+			}
+			if settings == nil {
+				settings = make(map[string]string)
+			}
+			settings[key] = strings.TrimSpace(value)
+		}
+	}
+	sort.Strings(unknown)
+	return settings, unknown
+}
+
+// nodeOverrideReader reads the overrides of the node goburn runs on
+type nodeOverrideReader struct {
+	k8sClient kubernetes.Interface
+	nodeName  string
+	warned    map[string]bool
+}
+
+// newNodeOverrideReader returns nil when there is no node to read
+func newNodeOverrideReader(k8sClient kubernetes.Interface, nodeName string) *nodeOverrideReader {
+	if k8sClient == nil || nodeName == "" {
+		return nil
+	}
+	return &nodeOverrideReader{k8sClient: k8sClient, nodeName: nodeName, warned: make(map[string]bool)}
+}
+
+// Refresh reads the node and stores its overrides in sources. It reports
+// whether they changed; a failed read keeps the previous overrides. Invalid
+// overrides are dropped, so they do not fail every later reload as well.
+func (r *nodeOverrideReader) Refresh(ctx context.Context, sources *configSources) bool {
+	if r == nil {
+		return false
+	}
+
+	node, err := r.k8sClient.CoreV1().Nodes().Get(ctx, r.nodeName, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("⚠️  Cannot read node %s, keeping its current overrides: %v", r.nodeName, err)
+		return false
+	}
+
+	settings, unknown := nodeOverrides(node)
+	for _, name := range unknown {
+		if !r.warned[name] {
+			r.warned[name] = true
+			log.Printf("⚠️  Ignoring unknown node override %s on %s", name, r.nodeName)
+		}
+	}
+	settings = r.dropInvalid(settings, *sources)
+
+	if reflect.DeepEqual(settings, sources.node) {
+		return false
+	}
+	sources.node = settings
+	log.Printf("🏷️  Node overrides on %s: %s", r.nodeName, formatOverrides(settings))
+	return true
+}
+
+// dropInvalid removes every override that makes an otherwise valid
+// configuration invalid, on its own or together with the others. When the
+// configuration is invalid without any override, e.g. a broken config file,
+// the overrides are kept and the reload reports that problem instead.
+func (r *nodeOverrideReader) dropInvalid(settings map[string]string, sources configSources) map[string]string {
+	sources.node = nil
+	if len(settings) == 0 {
+		return settings
+	}
+	if _, err := loadConfig(sources); err != nil {
+		return settings
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	valid := make(map[string]string)
+	for _, key := range keys {
+		sources.node = map[string]string{key: settings[key]}
+		if _, err := loadConfig(sources); err != nil {
+			r.warnInvalid(key+"="+settings[key], err)
+			continue
+		}
+		valid[key] = settings[key]
+	}
+
+	sources.node = valid
+	if _, err := loadConfig(sources); err != nil {
+		r.warnInvalid(formatOverrides(valid), err)
+		return nil
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	return valid
+}
+
+func (r *nodeOverrideReader) warnInvalid(overrides string, err error) {
+	if !r.warned[overrides] {
+		r.warned[overrides] = true
+		log.Printf("⚠️  Ignoring invalid node overrides %s on %s: %v", overrides, r.nodeName, err)
+	}
+}
+
+func formatOverrides(settings map[string]string) string {
+	if len(settings) == 0 {
+		return "none"
+	}
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + settings[key]
+	}
+	return strings.Join(parts, ", ")
+}
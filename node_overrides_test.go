@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeOverrides(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name: "pool-a-1",
+		Labels: map[string]string{
+			"goburn.io/target-cpu":    "60",
+			"goburn.io/enable-memory": "false",
+			"kubernetes.io/arch":      "arm64",
+		},
+		Annotations: map[string]string{
+			"goburn.io/enable-memory":    "true",
+			"goburn.io/min-network-mbps": " 50 ",
+			"goburn.io/target-cpux":      "70",
+		},
+	}}
+
+	settings, unknown := nodeOverrides(node)
+	expected := map[string]string{
+		"TARGET_CPU_UTILIZATION":       "60",
+		"ENABLE_MEMORY_UTILIZATION":    "true",
+		"MIN_NETWORK_UTILIZATION_MBPS": "50",
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected %v, got %v", expected, settings)
+	}
+	if !reflect.DeepEqual(unknown, []string{"goburn.io/target-cpux"}) {
+		t.Errorf("Expected the typo to be reported, got %v", unknown)
+	}
+
+	settings, unknown = nodeOverrides(&corev1.Node{})
+	if settings != nil || unknown != nil {
+		t.Errorf("Expected no overrides on a plain node, got %v %v", settings, unknown)
+	}
+}
+
+func TestNodeOverrideReader_Refresh(t *testing.T) {
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:        "test-node",
+		Annotations: map[string]string{"goburn.io/target-cpu": "60"},
+	}}
+	client := fake.NewSimpleClientset(node)
+	reader := newNodeOverrideReader(client, "test-node")
+
+	var sources configSources
+	if !reader.Refresh(ctx, &sources) {
+		t.Fatal("Expected the first read to report the overrides")
+	}
+	if reader.Refresh(ctx, &sources) {
+		t.Error("Expected no change on an unchanged node")
+	}
+
+	// Overrides win over the environment
+	t.Setenv("TARGET_CPU_UTILIZATION", "90")
+	config, err := loadConfig(sources)
+	if err != nil {
+		t.Fatalf("loadConfig failed: %v", err)
+	}
+	if config.TargetCPUUtilization != 60 {
+		t.Errorf("Expected the node override of 60%%, got %.1f%%", config.TargetCPUUtilization)
+	}
+
+	// Removing the annotation falls back to the environment
+	node.Annotations = nil
+	if _, err := client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update node: %v", err)
+	}
+	if !reader.Refresh(ctx, &sources) || sources.node != nil {
+		t.Errorf("Expected the overrides to be removed, got %v", sources.node)
+	}
+
+	// A missing node keeps the last overrides
+	sources.node = map[string]string{"MIN_CPU_UTILIZATION": "30"}
+	if newNodeOverrideReader(client, "missing").Refresh(ctx, &sources) || sources.node["MIN_CPU_UTILIZATION"] != "30" {
+		t.Errorf("Expected a failed read to keep the overrides, got %v", sources.node)
+	}
+
+	if newNodeOverrideReader(nil, "test-node") != nil || newNodeOverrideReader(client, "") != nil {
+		t.Error("Expected no reader without a client or node name")
+	}
+}
+
+func TestNodeOverrideReader_DropsInvalid(t *testing.T) {
+	t.Setenv("NODE_NAME", "test-node")
+	ctx := context.Background()
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name: "test-node",
+		Annotations: map[string]string{
+			"goburn.io/target-cpu": "abc",
+			"goburn.io/min-cpu":    "30",
+		},
+	}}
+	client := fake.NewSimpleClientset(node)
+	reader := newNodeOverrideReader(client, "test-node")
+
+	// The typo is dropped, the valid override is kept and reloads still work
+	var sources configSources
+	reader.Refresh(ctx, &sources)
+	if !reflect.DeepEqual(sources.node, map[string]string{"MIN_CPU_UTILIZATION": "30"}) {
+		t.Errorf("Expected only the valid override, got %v", sources.node)
+	}
+	if _, err := loadConfig(sources); err != nil {
+		t.Errorf("loadConfig failed after dropping the invalid override: %v", err)
+	}
+
+	// Overrides that are only invalid together are all dropped
+	node.Annotations = map[string]string{"goburn.io/target-cpu": "25", "goburn.io/min-cpu": "30"}
+	if _, err := client.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Failed to update node: %v", err)
+	}
+	reader.Refresh(ctx, &sources)
+	if sources.node != nil {
+		t.Errorf("Expected conflicting overrides to be dropped, got %v", sources.node)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// trafficPeer is another goburn pod that can receive generated traffic
+type trafficPeer struct {
+	nodeName string
+	addr     string
+}
+
+// peerDiscovery lists the other goburn pods and picks the ones this node
+// exchanges traffic with
+type peerDiscovery struct {
+	k8sClient kubernetes.Interface
+	namespace string
+	selector  string
+	nodeName  string
+	port      int
+	maxPeers  int
+}
+
+func newPeerDiscovery(config Config, k8sClient kubernetes.Interface) *peerDiscovery {
+	return &peerDiscovery{
+		k8sClient: k8sClient,
+		namespace: config.PodNamespace,
+		selector:  config.PeerLabelSelector,
+		nodeName:  config.NodeName,
+		port:      config.PeerPort,
+		maxPeers:  config.MaxPeers,
+	}
+}
+
+// Peers returns the sink addresses of the selected peers, sorted
+func (p *peerDiscovery) Peers(ctx context.Context) ([]string, error) {
+	pods, err := p.k8sClient.CoreV1().Pods(p.namespace).List(ctx, metav1.ListOptions{LabelSelector: p.selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peer pods: %v", err)
+	}
+
+	// One peer per node: traffic to a pod on this node never leaves it, and a
+	// second pod on the same node (e.g. mid rollout) shares the same NIC
+	seen := make(map[string]bool)
+	var candidates []trafficPeer
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Spec.NodeName == p.nodeName || seen[pod.Spec.NodeName] {
+			continue
+		}
+		seen[pod.Spec.NodeName] = true
+		candidates = append(candidates, trafficPeer{
+			nodeName: pod.Spec.NodeName,
+			addr:     net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(p.port)),
+		})
+	}
+
+	selected := selectPeers(p.nodeName, candidates, p.maxPeers)
+	addrs := make([]string, 0, len(selected))
+	for _, peer := range selected {
+		addrs = append(addrs, peer.addr)
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// selectPeers keeps the n candidates with the highest rendezvous score for
+// self. Each node only talks to a bounded number of peers, and pods coming or
+// going only change the choice of the nodes that ranked them.
+func selectPeers(self string, candidates []trafficPeer, n int) []trafficPeer {
+	sorted := append([]trafficPeer(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		si, sj := rendezvousScore(self, sorted[i].nodeName), rendezvousScore(self, sorted[j].nodeName)
+		if si != sj {
+			return si > sj
+		}
+		return sorted[i].nodeName < sorted[j].nodeName
+	})
+
+	if n >= 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+func rendezvousScore(self, peer string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(self))
+	h.Write([]byte{0})
+	h.Write([]byte(peer))
+	return h.Sum64()
+}
+
+// peerWorker re-resolves peers every interval and points the traffic
+// generator at them. Lookup errors keep the previous peers.
+func (rb *ResourceBurner) peerWorker(ctx context.Context) {
+	config := rb.currentConfig()
+	discovery := newPeerDiscovery(config, rb.k8sClient)
+	interval := config.PeerRefreshInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var current []string
+	for {
+		peers, err := discovery.Peers(ctx)
+		if err != nil {
+			log.Printf("⚠️  Peer discovery failed, keeping %d peers: %v", len(current), err)
+		} else if !equalStrings(peers, current) {
+			if len(peers) == 0 {
+				log.Printf("🔗 No traffic peers found for %q in namespace %s", config.PeerLabelSelector, config.PodNamespace)
+			} else {
+				log.Printf("🔗 Traffic peers: %s", strings.Join(peers, ", "))
+			}
+			current = peers
+			rb.traffic.SetTargets(peers)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
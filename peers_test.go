@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testPeerPod(name, node, ip string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "goburn",
+			Labels:    map[string]string{"app": "goburn"},
+		},
+		Spec:   corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{Phase: phase, PodIP: ip},
+	}
+}
+
+func TestPeerDiscovery_Peers(t *testing.T) {
+	other := testPeerPod("other", "node-x", "10.0.0.9", corev1.PodRunning)
+	other.Labels = map[string]string{"app": "something-else"}
+
+	k8sClient := fake.NewSimpleClientset(
+		testPeerPod("self", "node-a", "10.0.0.1", corev1.PodRunning),
+		testPeerPod("b", "node-b", "10.0.0.2", corev1.PodRunning),
+		testPeerPod("b-old", "node-b", "10.0.0.2", corev1.PodRunning),
+		testPeerPod("c", "node-c", "10.0.0.3", corev1.PodRunning),
+		testPeerPod("pending", "node-d", "", corev1.PodPending),
+		other,
+	)
+
+	discovery := newPeerDiscovery(Config{
+		NodeName:          "node-a",
+		PodNamespace:      "goburn",
+		PeerLabelSelector: "app=goburn",
+		PeerPort:          9000,
+		MaxPeers:          10,
+	}, k8sClient)
+
+	peers, err := discovery.Peers(context.Background())
+	if err != nil {
+		t.Fatalf("Peers() error = %v", err)
+	}
+
+	expected := []string{"10.0.0.2:9000", "10.0.0.3:9000"}
+	if !equalStrings(peers, expected) {
+		t.Errorf("Peers() = %v, want %v", peers, expected)
+	}
+}
+
+func TestPeerDiscovery_MaxPeers(t *testing.T) {
+	var objects []corev1.Pod
+	k8sClient := fake.NewSimpleClientset()
+	for i := 0; i < 20; i++ {
+		pod := testPeerPod(fmt.Sprintf("pod-%d", i), fmt.Sprintf("node-%d", i), fmt.Sprintf("10.0.1.%d", i), corev1.PodRunning)
+		objects = append(objects, *pod)
+		if _, err := k8sClient.CoreV1().Pods("goburn").Create(context.Background(), pod, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("failed to create pod: %v", err)
+		}
+	}
+
+	config := Config{NodeName: "node-0", PodNamespace: "goburn", PeerLabelSelector: "app=goburn", PeerPort: 9000, MaxPeers: 3}
+	peers, err := newPeerDiscovery(config, k8sClient).Peers(context.Background())
+	if err != nil {
+		t.Fatalf("Peers() error = %v", err)
+	}
+	if len(peers) != 3 {
+		t.Fatalf("Expected 3 peers, got %v", peers)
+	}
+
+	// Removing a pod that was not selected leaves the choice alone
+	for _, pod := range objects[1:] {
+		addr := pod.Status.PodIP + ":9000"
+		if contains(peers, addr) {
+			continue
+		}
+		if err := k8sClient.CoreV1().Pods("goburn").Delete(context.Background(), pod.Name, metav1.DeleteOptions{}); err != nil {
+			t.Fatalf("failed to delete pod: %v", err)
+		}
+		break
+	}
+	after, err := newPeerDiscovery(config, k8sClient).Peers(context.Background())
+	if err != nil {
+		t.Fatalf("Peers() error = %v", err)
+	}
+	if !equalStrings(peers, after) {
+		t.Errorf("Peers changed from %v to %v after removing an unselected pod", peers, after)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSelectPeers(t *testing.T) {
+	candidates := []trafficPeer{
+		{nodeName: "node-b", addr: "b"},
+		{nodeName: "node-c", addr: "c"},
+		{nodeName: "node-d", addr: "d"},
+		{nodeName: "node-e", addr: "e"},
+	}
+
+	first := selectPeers("node-a", candidates, 2)
+	if len(first) != 2 {
+		t.Fatalf("Expected 2 peers, got %d", len(first))
+	}
+
+	// The choice does not depend on the order pods are listed in
+	reversed := []trafficPeer{candidates[3], candidates[2], candidates[1], candidates[0]}
+	second := selectPeers("node-a", reversed, 2)
+	if first[0] != second[0] || first[1] != second[1] {
+		t.Errorf("selectPeers() depends on input order: %v vs %v", first, second)
+	}
+
+	if all := selectPeers("node-a", candidates, 10); len(all) != 4 {
+		t.Errorf("Expected all 4 candidates when below the cap, got %d", len(all))
+	}
+	if none := selectPeers("node-a", candidates, 0); len(none) != 0 {
+		t.Errorf("Expected no peers with a cap of 0, got %d", len(none))
+	}
+}
+
+func TestTrafficGenerator_SetTargets(t *testing.T) {
+	g := newTrafficGenerator(Config{NetworkMode: NetworkModeUDP, NetworkStreams: 1})
+	defer g.Stop()
+
+	// With a rate but no peers yet nothing runs
+	g.SetRate(1)
+	if g.cancel != nil {
+		t.Fatal("Expected no streams without targets")
+	}
+
+	g.SetTargets([]string{"127.0.0.1:9"})
+	if g.cancel == nil {
+		t.Error("Expected streams to start once peers are known")
+	}
+	if targets := g.Targets(); len(targets) != 1 || targets[0] != "127.0.0.1:9" {
+		t.Errorf("Targets() = %v", targets)
+	}
+
+	g.SetTargets(nil)
+	if g.cancel != nil {
+		t.Error("Expected streams to stop when every peer is gone")
+	}
+}
+
+func TestResourceBurner_PeerWorker(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.k8sClient = fake.NewSimpleClientset(
+		testPeerPod("self", "test-node", "10.0.0.1", corev1.PodRunning),
+		testPeerPod("peer", "node-b", "10.0.0.2", corev1.PodRunning),
+	)
+	rb.config.PodNamespace = "goburn"
+	rb.config.PeerLabelSelector = "app=goburn"
+	rb.config.PeerPort = 9000
+	rb.config.MaxPeers = 3
+	rb.config.PeerRefreshInterval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		rb.peerWorker(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rb.traffic.Targets()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if targets := rb.traffic.Targets(); len(targets) != 1 || targets[0] != "10.0.0.2:9000" {
+		t.Errorf("Targets() = %v, want [10.0.0.2:9000]", targets)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used when PERCENTILE_WINDOW or CPU_PERCENTILE are not set
+const (
+	defaultPercentileWindow = time.Hour
+	defaultCPUPercentile    = 95.0
+)
+
+// percentileBins is the histogram resolution: 0.1% steps from 0% to 100%,
+// or a thousandth of the scale for values that are not percentages
+const percentileBins = 1001
+
+// percentileProjectionLookback is the recent history that future samples are
+// assumed to look like when projecting the percentile at the end of the window
+const percentileProjectionLookback = time.Hour
+
+// percentileBucket holds the samples of one minute as histogram bins
+type percentileBucket struct {
+	minute int64
+	bins   []uint16
+}
+
+// percentileWindow keeps samples over a rolling duration. Samples are
+// stored as 0.1% histogram bins in per-minute buckets, two bytes each, and a
+// histogram of the whole window is kept up to date as buckets expire, so a 7d
+// window at 30s intervals costs a few hundred KB and a percentile lookup is a
+// single pass over 1001 counters.
+type percentileWindow struct {
+	window         time.Duration
+	percentile     float64
+	sampleInterval time.Duration
+	scale          float64 // value of the top bin, 100 for percentages
+
+	buckets   []percentileBucket
+	histogram [percentileBins]uint32
+	count     int
+}
+
+func newPercentileWindow(window time.Duration, percentile float64, sampleInterval time.Duration) *percentileWindow {
+	return &percentileWindow{window: window, percentile: percentile, sampleInterval: sampleInterval}
+}
+
+func (w *percentileWindow) Window() time.Duration {
+	if w.window <= 0 {
+		return defaultPercentileWindow
+	}
+	return w.window
+}
+
+// Scale returns the largest value the window can tell apart; larger samples count as the scale
+func (w *percentileWindow) Scale() float64 {
+	if w.scale <= 0 {
+		return 100
+	}
+	return w.scale
+}
+
+func (w *percentileWindow) bin(value float64) uint16 {
+	return percentileBin(value / w.Scale() * 100)
+}
+
+func (w *percentileWindow) value(bin int) float64 {
+	return float64(bin) / 10 * w.Scale() / 100
+}
+
+func (w *percentileWindow) Percentile() float64 {
+	if w.percentile <= 0 || w.percentile > 100 {
+		return defaultCPUPercentile
+	}
+	return w.percentile
+}
+
+// Count returns the number of samples in the window
+func (w *percentileWindow) Count() int {
+	return w.count
+}
+
+// Add records a sample taken at now and drops samples that fell out of the window
+func (w *percentileWindow) Add(value float64, now time.Time) {
+	w.expire(now)
+
+	bin := w.bin(value)
+	minute := now.Unix() / 60
+	if n := len(w.buckets); n == 0 || w.buckets[n-1].minute != minute {
+		w.buckets = append(w.buckets, percentileBucket{minute: minute})
+	}
+	last := &w.buckets[len(w.buckets)-1]
+	last.bins = append(last.bins, bin)
+	w.histogram[bin]++
+	w.count++
+}
+
+// Reset drops every sample
+func (w *percentileWindow) Reset() {
+	w.buckets = nil
+	w.histogram = [percentileBins]uint32{}
+	w.count = 0
+}
+
+func (w *percentileWindow) expire(now time.Time) {
+	oldest := now.Add(-w.Window()).Unix() / 60
+	drop := 0
+	for drop < len(w.buckets) && w.buckets[drop].minute < oldest {
+		for _, bin := range w.buckets[drop].bins {
+			w.histogram[bin]--
+			w.count--
+		}
+		drop++
+	}
+	// append reallocates once the backing array is used up, which frees the dropped buckets
+	w.buckets = w.buckets[drop:]
+}
+
+// Value returns the configured percentile over the window, by nearest rank
+func (w *percentileWindow) Value() float64 {
+	if w.count == 0 {
+		return 0
+	}
+
+	rank := max(1, min(int64(math.Ceil(w.Percentile()/100*float64(w.count))), int64(w.count)))
+
+	var seen int64
+	for bin, n := range w.histogram {
+		seen += int64(n)
+		if seen >= rank {
+			return w.value(bin)
+		}
+	}
+	return w.Scale()
+}
+
+// Projected returns the percentile the window will report once it spans its
+// full duration from the oldest sample, assuming the remaining samples look
+// like the last hour. A full window projects its current value.
+func (w *percentileWindow) Projected(now time.Time) float64 {
+	if w.count == 0 {
+		return 0
+	}
+
+	start := time.Unix(w.buckets[0].minute*60, 0)
+	remaining := start.Add(w.Window()).Sub(now)
+	interval := w.sampleInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	future := float64(remaining / interval)
+	if future <= 0 {
+		return w.Value()
+	}
+
+	// Distribution of the recent samples, scaled up to the remaining ones
+	var recent [percentileBins]float64
+	var recentCount float64
+	since := now.Add(-percentileProjectionLookback).Unix() / 60
+	for i := len(w.buckets) - 1; i >= 0 && w.buckets[i].minute >= since; i-- {
+		for _, bin := range w.buckets[i].bins {
+			recent[bin]++
+			recentCount++
+		}
+	}
+	if recentCount == 0 {
+		return w.Value()
+	}
+
+	total := float64(w.count) + future
+	rank := math.Ceil(w.Percentile() / 100 * total)
+	var seen float64
+	for bin := range w.histogram {
+		seen += float64(w.histogram[bin]) + recent[bin]*future/recentCount
+		if seen >= rank {
+			return w.value(bin)
+		}
+	}
+	return w.Scale()
+}
+
+func percentileBin(cpuPercent float64) uint16 {
+	bin := math.Round(cpuPercent * 10)
+	if bin < 0 || math.IsNaN(bin) {
+		return 0
+	}
+	if bin >= percentileBins {
+		return percentileBins - 1
+	}
+	return uint16(bin)
+}
+
+// parseWindowDuration parses a Go duration that may also use a "d" (day)
+// suffix, e.g. "7d" or "1d12h"
+func parseWindowDuration(value string) (time.Duration, error) {
+	days, rest, found := strings.Cut(value, "d")
+	if !found {
+		return time.ParseDuration(value)
+	}
+
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid number of days in %q", value)
+	}
+	d := time.Duration(n) * 24 * time.Hour
+	if rest != "" {
+		extra, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, err
+		}
+		d += extra
+	}
+	return d, nil
+}
+
+// Buckets returns a copy of the per-minute buckets, oldest first
+func (w *percentileWindow) Buckets() []percentileBucket {
+	buckets := make([]percentileBucket, len(w.buckets))
+	for i, b := range w.buckets {
+		buckets[i] = percentileBucket{minute: b.minute, bins: append([]uint16(nil), b.bins...)}
+	}
+	return buckets
+}
+
+// Restore replaces the samples with previously saved buckets and drops the
+// ones that are already outside the window
+func (w *percentileWindow) Restore(buckets []percentileBucket, now time.Time) {
+	w.Reset()
+	for _, b := range buckets {
+		bins := append([]uint16(nil), b.bins...)
+		for _, bin := range bins {
+			w.histogram[bin]++
+		}
+		w.count += len(bins)
+		w.buckets = append(w.buckets, percentileBucket{minute: b.minute, bins: bins})
+	}
+	w.expire(now)
+}
+
+// CountAtLeast returns how many samples in the window are at or above value
+func (w *percentileWindow) CountAtLeast(value float64) int {
+	count := 0
+	for bin := int(w.bin(value)); bin < percentileBins; bin++ {
+		count += int(w.histogram[bin])
+	}
+	return count
+}
+
+// PlannedPercentile returns the percentile a full window settles at when a
+// share of the samples is taken at burstPercent and the rest look like the
+// last hour without bursts
+func (w *percentileWindow) PlannedPercentile(now time.Time, share, burstPercent float64) float64 {
+	var quiet [percentileBins]float64
+	var quietCount float64
+	burstBin := w.bin(burstPercent)
+	since := now.Add(-percentileProjectionLookback).Unix() / 60
+	for i := len(w.buckets) - 1; i >= 0 && w.buckets[i].minute >= since; i-- {
+		for _, bin := range w.buckets[i].bins {
+			if bin < burstBin {
+				quiet[bin]++
+				quietCount++
+			}
+		}
+	}
+	if quietCount == 0 {
+		return burstPercent
+	}
+
+	share = math.Max(0, math.Min(share, 1))
+	rank := w.Percentile() / 100
+	var seen float64
+	for bin := range quiet {
+		seen += quiet[bin] / quietCount * (1 - share)
+		if bin == int(burstBin) {
+			seen += share
+		}
+		if seen >= rank {
+			return w.value(bin)
+		}
+	}
+	return w.Scale()
+}
+
+// Mean returns the average sample, to the resolution of the histogram
+func (w *percentileWindow) Mean() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	var sum float64
+	for bin, n := range w.histogram {
+		sum += w.value(bin) * float64(n)
+	}
+	return sum / float64(w.count)
+}
+
+// Min returns the smallest sample in the window
+func (w *percentileWindow) Min() float64 {
+	for bin, n := range w.histogram {
+		if n > 0 {
+			return w.value(bin)
+		}
+	}
+	return 0
+}
+
+// TimeToViolation returns how long the percentile stays at or above minimum
+// if every sample from now on falls below it: new samples dilute the window
+// while the high samples in the oldest buckets expire. It is 0 when the
+// percentile is already below the minimum.
+func (w *percentileWindow) TimeToViolation(now time.Time, minimum float64) time.Duration {
+	interval := w.sampleInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	perMinute := float64(time.Minute) / float64(interval)
+	minBin := w.bin(minimum)
+
+	high := w.CountAtLeast(minimum)
+	samples := float64(w.count)
+	if high < highSamplesNeeded(w.count, w.Percentile()) || w.count == 0 {
+		return 0
+	}
+
+	next := 0
+	windowMinutes := int(w.Window() / time.Minute)
+	for step := 1; step <= windowMinutes+1; step++ {
+		samples += perMinute
+		oldest := now.Add(time.Duration(step)*time.Minute-w.Window()).Unix() / 60
+		for ; next < len(w.buckets) && w.buckets[next].minute < oldest; next++ {
+			for _, bin := range w.buckets[next].bins {
+				samples--
+				if bin >= minBin {
+					high--
+				}
+			}
+		}
+		if high < highSamplesNeeded(int(math.Round(samples)), w.Percentile()) {
+			return time.Duration(step) * time.Minute
+		}
+	}
+	return w.Window()
+}
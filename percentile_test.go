@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPercentileWindow_Value(t *testing.T) {
+	w := newPercentileWindow(time.Hour, 50, 30*time.Second)
+	now := time.Now()
+
+	for _, v := range []float64{10, 20, 30, 40} {
+		w.Add(v, now)
+	}
+	if got := w.Value(); got != 20 {
+		t.Errorf("p50 = %.1f, want 20", got)
+	}
+
+	// Values are kept to 0.1% and clamped to 0-100
+	w.Reset()
+	w.Add(37.26, now)
+	w.Add(-5, now)
+	w.Add(250, now)
+	w.percentile = 100
+	if got := w.Value(); got != 100 {
+		t.Errorf("p100 = %.1f, want clamped to 100", got)
+	}
+	w.percentile = 60
+	if got := w.Value(); got != 37.3 {
+		t.Errorf("p60 = %.1f, want 37.3", got)
+	}
+}
+
+func TestPercentileWindow_Defaults(t *testing.T) {
+	w := newPercentileWindow(0, 0, 0)
+	if w.Window() != defaultPercentileWindow {
+		t.Errorf("Window() = %v, want %v", w.Window(), defaultPercentileWindow)
+	}
+	if w.Percentile() != defaultCPUPercentile {
+		t.Errorf("Percentile() = %v, want %v", w.Percentile(), defaultCPUPercentile)
+	}
+	if w.Value() != 0 || w.Projected(time.Now()) != 0 {
+		t.Error("Expected 0 for an empty window")
+	}
+}
+
+func TestPercentileWindow_SevenDays(t *testing.T) {
+	w := newPercentileWindow(7*24*time.Hour, 95, 30*time.Second)
+	start := time.Unix(1700000040, 0)
+
+	// Eight days of 30s samples: a busy first day that expires, then idle
+	var now time.Time
+	for i := 0; i < 8*24*120; i++ {
+		now = start.Add(time.Duration(i) * 30 * time.Second)
+		v := 5.0
+		if i < 24*120 {
+			v = 80
+		}
+		w.Add(v, now)
+	}
+
+	if w.Count() > 7*24*120+2 {
+		t.Errorf("Count() = %d, want at most a week of samples", w.Count())
+	}
+	if len(w.buckets) > 7*24*60+1 {
+		t.Errorf("%d buckets, want one per minute", len(w.buckets))
+	}
+	if got := w.Value(); got != 5 {
+		t.Errorf("p95 = %.1f, want 5 once the busy day expired", got)
+	}
+}
+
+func TestPercentileWindow_Projected(t *testing.T) {
+	w := newPercentileWindow(24*time.Hour, 95, time.Minute)
+	start := time.Unix(1700000040, 0)
+
+	// A busy hour, then two idle hours: the window is 1/8 full
+	var now time.Time
+	for i := 0; i < 180; i++ {
+		now = start.Add(time.Duration(i) * time.Minute)
+		v := 60.0
+		if i >= 60 {
+			v = 10
+		}
+		w.Add(v, now)
+	}
+
+	if got := w.Value(); got != 60 {
+		t.Errorf("current p95 = %.1f, want 60", got)
+	}
+	// 21 more idle hours push the busy hour below the 5% tail
+	if got := w.Projected(now); got != 10 {
+		t.Errorf("projected p95 = %.1f, want 10", got)
+	}
+
+	// A full window projects its current value
+	full := newPercentileWindow(time.Hour, 95, time.Minute)
+	for i := 0; i < 120; i++ {
+		now = start.Add(time.Duration(i) * time.Minute)
+		full.Add(float64(i%60), now)
+	}
+	if full.Projected(now) != full.Value() {
+		t.Errorf("Projected() = %.1f, want the current %.1f", full.Projected(now), full.Value())
+	}
+}
+
+func TestParseWindowDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"7d", 7 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"0d", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseWindowDuration(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseWindowDuration(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"d", "xd", "-1d", "7d3", "week"} {
+		if _, err := parseWindowDuration(value); err == nil {
+			t.Errorf("parseWindowDuration(%q) expected error, got nil", value)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pressureStats is one line of a PSI file in /proc/pressure
+type pressureStats struct {
+	Avg10 float64
+	Total uint64 // cumulative stall time in microseconds
+}
+
+// pressure holds the "some" and "full" lines of a PSI file. "some" is the
+// share of time at least one task stalled on the resource.
+type pressure struct {
+	Some pressureStats
+	Full pressureStats
+}
+
+// readPressure reads /proc/pressure/<resource>, e.g. "memory" or "cpu"
+func readPressure(resource string) (pressure, error) {
+	data, err := os.ReadFile(procPath("pressure", resource))
+	if err != nil {
+		return pressure{}, fmt.Errorf("failed to read /proc/pressure/%s: %v", resource, err)
+	}
+	return parsePressure(string(data))
+}
+
+// readTenantCPUPressure returns the CPU pressure of the tenant pod that stalls
+// the most. goburn's own pod is left out, so its runnable burn threads never
+// count as pressure; PSI does not add up across cgroups, so the worst tenant
+// stands for all of them.
+func readTenantCPUPressure() (pressure, error) {
+	own, err := ownCgroupPath()
+	if err != nil {
+		return pressure{}, err
+	}
+	root, pod, err := kubepodsCgroups(own)
+	if err != nil {
+		return pressure{}, err
+	}
+	tenants, err := tenantCgroups(root, pod)
+	if err != nil {
+		return pressure{}, err
+	}
+
+	var worst pressure
+	for _, dir := range tenants {
+		data, err := os.ReadFile(filepath.Join(dir, "cpu.pressure"))
+		if err != nil {
+			// The pod may just have been deleted
+			continue
+		}
+		p, err := parsePressure(string(data))
+		if err != nil {
+			return pressure{}, fmt.Errorf("failed to parse %s: %v", filepath.Join(dir, "cpu.pressure"), err)
+		}
+		if p.Some.Avg10 > worst.Some.Avg10 {
+			worst = p
+		}
+	}
+	return worst, nil
+}
+
+// kubepodsCgroups returns the kubepods cgroup and the cgroup of goburn's own
+// pod, given goburn's cgroup: a container cgroup, or its control cgroup when
+// the burn runs in a sibling
+func kubepodsCgroups(own string) (root, pod string, err error) {
+	container := own
+	if filepath.Base(container) == burnCgroupControl {
+		container = filepath.Dir(container)
+	}
+	pod = filepath.Dir(container)
+	for dir := filepath.Dir(pod); dir != cgroupRoot && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if base := filepath.Base(dir); base == "kubepods" || base == "kubepods.slice" {
+			return dir, pod, nil
+		}
+	}
+	return "", "", fmt.Errorf("%s is not in a kubepods cgroup", own)
+}
+
+// tenantCgroups lists the cgroups under root that hold every pod but own: the
+// siblings of own and of each of its ancestors, e.g. the other QoS classes
+func tenantCgroups(root, own string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", root, err)
+	}
+
+	var tenants []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		switch {
+		case dir == own:
+		case strings.HasPrefix(own, dir+string(filepath.Separator)):
+			nested, err := tenantCgroups(dir, own)
+			if err != nil {
+				return nil, err
+			}
+			tenants = append(tenants, nested...)
+		default:
+			tenants = append(tenants, dir)
+		}
+	}
+	return tenants, nil
+}
+
+func parsePressure(data string) (pressure, error) {
+	var p pressure
+	found := false
+	for _, line := range strings.Split(data, "\n") {
+		kind, fields, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+
+		var stats *pressureStats
+		switch kind {
+		case "some":
+			stats = &p.Some
+			found = true
+		case "full":
+			stats = &p.Full
+		default:
+			continue
+		}
+
+		for _, field := range strings.Fields(fields) {
+			key, value, _ := strings.Cut(field, "=")
+			var err error
+			switch key {
+			case "avg10":
+				stats.Avg10, err = strconv.ParseFloat(value, 64)
+			case "total":
+				stats.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return pressure{}, fmt.Errorf("failed to parse pressure %s %s: %v", kind, key, err)
+			}
+		}
+	}
+	if !found {
+		return pressure{}, fmt.Errorf("pressure data has no \"some\" line")
+	}
+	return p, nil
+}
+
+// pressureTracker turns the cumulative stall total into the percentage of
+// time stalled since the previous sample, which reacts much faster than avg10
+type pressureTracker struct {
+	total    uint64
+	lastTime time.Time
+	valid    bool
+}
+
+func (t *pressureTracker) update(total uint64, now time.Time) (float64, bool) {
+	defer func() {
+		t.total = total
+		t.lastTime = now
+		t.valid = true
+	}()
+
+	if !t.valid || total < t.total {
+		return 0, false
+	}
+	elapsed := now.Sub(t.lastTime)
+	if elapsed <= 0 {
+		return 0, false
+	}
+	return float64(total-t.total) / float64(elapsed.Microseconds()) * 100, true
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testMemoryPressure = `some avg10=12.50 avg60=3.10 avg300=0.80 total=123456
+full avg10=4.00 avg60=1.00 avg300=0.20 total=45678
+`
+
+func TestParsePressure(t *testing.T) {
+	p, err := parsePressure(testMemoryPressure)
+	if err != nil {
+		t.Fatalf("parsePressure() error = %v", err)
+	}
+	if p.Some.Avg10 != 12.5 || p.Some.Total != 123456 {
+		t.Errorf("Some = %+v, want avg10 12.5 total 123456", p.Some)
+	}
+	if p.Full.Avg10 != 4 || p.Full.Total != 45678 {
+		t.Errorf("Full = %+v, want avg10 4 total 45678", p.Full)
+	}
+
+	// CPU pressure on older kernels only has a "some" line
+	if _, err := parsePressure("some avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"); err != nil {
+		t.Errorf("parsePressure(some only) error = %v", err)
+	}
+
+	for _, data := range []string{"", "full avg10=1.00 total=5\n", "some avg10=abc total=5\n", "some avg10=1.00 total=-1\n"} {
+		if _, err := parsePressure(data); err == nil {
+			t.Errorf("parsePressure(%q) expected error, got nil", data)
+		}
+	}
+}
+
+func TestReadPressure(t *testing.T) {
+	writeProcFixture(t, map[string]string{"pressure/memory": testMemoryPressure})
+
+	p, err := readPressure("memory")
+	if err != nil {
+		t.Fatalf("readPressure() error = %v", err)
+	}
+	if p.Some.Total != 123456 {
+		t.Errorf("Some.Total = %d, want 123456", p.Some.Total)
+	}
+	if _, err := readPressure("cpu"); err == nil {
+		t.Error("Expected error for missing pressure file, got nil")
+	}
+}
+
+func TestReadTenantCPUPressure(t *testing.T) {
+	writeProcFixture(t, map[string]string{"self/cgroup": "0::/kubepods/burstable/pod-goburn/ctr/control\n"})
+	root := t.TempDir()
+	oldRoot := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() { cgroupRoot = oldRoot })
+
+	// goburn's own control and burn cgroups stall the most, but do not count
+	for dir, avg10 := range map[string]string{
+		"kubepods/burstable/pod-goburn/ctr/control": "90.00",
+		"kubepods/burstable/pod-goburn/ctr/burn":    "95.00",
+		"kubepods/burstable/pod-a":                  "30.00",
+		"kubepods/besteffort":                       "5.00",
+		"kubepods/pod-guaranteed":                   "20.00",
+	} {
+		path := filepath.Join(root, dir)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatalf("failed to create cgroup fixture: %v", err)
+		}
+		writeCgroupFile(t, path, "cpu.pressure", "some avg10="+avg10+" avg60=0.00 avg300=0.00 total=1\n")
+	}
+
+	p, err := readTenantCPUPressure()
+	if err != nil {
+		t.Fatalf("readTenantCPUPressure() error = %v", err)
+	}
+	if p.Some.Avg10 != 30 {
+		t.Errorf("Some.Avg10 = %.1f, want the 30%% of the most stalled tenant", p.Some.Avg10)
+	}
+
+	writeProcFixture(t, map[string]string{"self/cgroup": "0::/system.slice/goburn.service\n"})
+	if _, err := readTenantCPUPressure(); err == nil {
+		t.Error("Expected error outside of a kubepods cgroup, got nil")
+	}
+}
+
+func TestPressureTracker(t *testing.T) {
+	var tracker pressureTracker
+	start := time.Now()
+
+	if _, ok := tracker.update(1000000, start); ok {
+		t.Error("Expected no stall percentage from the first sample")
+	}
+
+	// 250ms of stall in one second
+	stall, ok := tracker.update(1250000, start.Add(time.Second))
+	if !ok || stall != 25 {
+		t.Errorf("update() = %.1f%% (ok %v), want 25%%", stall, ok)
+	}
+
+	// A counter reset is not a negative stall
+	if _, ok := tracker.update(10, start.Add(2*time.Second)); ok {
+		t.Error("Expected no stall percentage after a counter reset")
+	}
+}
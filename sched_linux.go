@@ -0,0 +1,40 @@
+//go:build linux
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// schedIdle is SCHED_IDLE from <linux/sched.h>
+const schedIdle = 5
+
+type schedParam struct {
+	priority int32
+}
+
+// lowerThreadPriority lowers the priority of the calling OS thread only. The
+// caller must be locked to its thread, otherwise the Go scheduler would carry
+// other goroutines onto it.
+func lowerThreadPriority(policy string, nice int) error {
+	tid := syscall.Gettid()
+
+	switch policy {
+	case CPUPriorityIdle:
+		param := schedParam{priority: 0}
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER,
+			uintptr(tid), schedIdle, uintptr(unsafe.Pointer(&param))); errno != 0 {
+			return fmt.Errorf("failed to set SCHED_IDLE: %v", errno)
+		}
+	case CPUPriorityNice:
+		// On Linux setpriority with a thread id only affects that thread
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, nice); err != nil {
+			return fmt.Errorf("failed to set nice %d: %v", nice, err)
+		}
+	default:
+		return fmt.Errorf("unknown CPU priority %q", policy)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package main
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+// onLockedThread runs fn on a fresh OS thread that is discarded afterwards,
+// so a lowered priority never leaks into other tests
+func onLockedThread(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runtime.LockOSThread()
+		fn()
+	}()
+	<-done
+}
+
+func TestLowerThreadPriority_Idle(t *testing.T) {
+	onLockedThread(func() {
+		if err := lowerThreadPriority(CPUPriorityIdle, 0); err != nil {
+			t.Errorf("lowerThreadPriority(idle) error = %v", err)
+			return
+		}
+		policy, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETSCHEDULER, uintptr(syscall.Gettid()), 0, 0)
+		if errno != 0 {
+			t.Errorf("sched_getscheduler error = %v", errno)
+			return
+		}
+		if policy != schedIdle {
+			t.Errorf("scheduling policy = %d, want SCHED_IDLE (%d)", policy, schedIdle)
+		}
+	})
+}
+
+func TestLowerThreadPriority_Nice(t *testing.T) {
+	onLockedThread(func() {
+		if err := lowerThreadPriority(CPUPriorityNice, 15); err != nil {
+			t.Errorf("lowerThreadPriority(nice) error = %v", err)
+			return
+		}
+		// The raw getpriority syscall returns 20 - nice
+		prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, syscall.Gettid())
+		if err != nil {
+			t.Errorf("getpriority error = %v", err)
+			return
+		}
+		if 20-prio != 15 {
+			t.Errorf("nice = %d, want 15", 20-prio)
+		}
+	})
+}
+
+func TestLowerThreadPriority_Unknown(t *testing.T) {
+	onLockedThread(func() {
+		if err := lowerThreadPriority("realtime", 0); err == nil {
+			t.Error("Expected error for unknown priority, got nil")
+		}
+	})
+}
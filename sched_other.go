@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// lowerThreadPriority is only implemented on Linux; workers keep the normal priority
+func lowerThreadPriority(policy string, nice int) error {
+	return fmt.Errorf("CPU priority %q is not supported on this platform", policy)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// cgroupRoot is where the cgroup v2 hierarchy is mounted; tests point it at a fixture
+var cgroupRoot = "/sys/fs/cgroup"
+
+// SelfUsage is what goburn itself consumes
+type SelfUsage struct {
+	CPUMillicores float64
+	MemoryBytes   int64
+	Source        string
+}
+
+// WorkloadUsage splits node usage into goburn's own share and everything else
+type WorkloadUsage struct {
+	OwnCPUPercent        float64
+	OwnMemoryPercent     float64
+	ForeignCPUPercent    float64
+	ForeignMemoryPercent float64
+	Source               string
+}
+
+// selfUsageTracker turns the cumulative cgroup CPU counter into a rate
+type selfUsageTracker struct {
+	mu       sync.Mutex
+	prevUsec uint64
+	prevTime time.Time
+	valid    bool
+}
+
+// update records a cpu.stat usage_usec sample and returns the millicores used
+// since the previous one
+func (t *selfUsageTracker) update(usageUsec uint64, now time.Time) (millicores float64, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prevUsec, prevTime, valid := t.prevUsec, t.prevTime, t.valid
+	t.prevUsec, t.prevTime, t.valid = usageUsec, now, true
+
+	elapsed := now.Sub(prevTime)
+	if !valid || elapsed <= 0 || usageUsec < prevUsec {
+		return 0, false
+	}
+	return float64(usageUsec-prevUsec) / float64(elapsed.Microseconds()) * 1000, true
+}
+
+// ownCgroupPath returns the cgroup v2 directory of this process
+func ownCgroupPath() (string, error) {
+	data, err := os.ReadFile(procPath("self", "cgroup"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read own cgroup: %v", err)
+	}
+
+	// The unified hierarchy is the "0::<path>" entry
+	for _, line := range strings.Split(string(data), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(cgroupRoot, path), nil
+		}
+	}
+	return "", errors.New("no cgroup v2 entry in /proc/self/cgroup")
+}
+
+// readCgroupUsage reads cumulative CPU time and current memory of a cgroup
+func readCgroupUsage(dir string) (usageUsec uint64, memoryBytes int64, err error) {
+	file, err := os.Open(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open cpu.stat: %v", err)
+	}
+	defer file.Close()
+
+	found := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usageUsec, err = strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to parse usage_usec: %v", err)
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return 0, 0, errors.New("usage_usec not found in cpu.stat")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read memory.current: %v", err)
+	}
+	memoryBytes, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to parse memory.current: %v", err)
+	}
+	return usageUsec, memoryBytes, nil
+}
+
+// cgroupSelfUsage measures goburn from its own cgroup. The CPU rate needs two
+// samples, so the first call only records a baseline.
+func (rb *ResourceBurner) cgroupSelfUsage(now time.Time) (SelfUsage, error) {
+	dir, err := ownCgroupPath()
+	if err != nil {
+		return SelfUsage{}, err
+	}
+	// Burning in a child moves goburn into the control leaf; the parent also
+	// holds the burn cgroup, whose usage is goburn's as well
+	if filepath.Base(dir) == burnCgroupControl {
+		dir = filepath.Dir(dir)
+	}
+	usageUsec, memoryBytes, err := readCgroupUsage(dir)
+	if err != nil {
+		return SelfUsage{}, err
+	}
+
+	millicores, ok := rb.selfTracker.update(usageUsec, now)
+	if !ok {
+		return SelfUsage{}, errors.New("waiting for a second cgroup CPU sample")
+	}
+	return SelfUsage{CPUMillicores: millicores, MemoryBytes: memoryBytes, Source: "cgroup"}, nil
+}
+
+// podMetricsSelfUsage measures goburn from the metrics-server PodMetrics of its own pod
+func (rb *ResourceBurner) podMetricsSelfUsage(ctx context.Context) (SelfUsage, error) {
+	if rb.config.PodName == "" {
+		return SelfUsage{}, errors.New("POD_NAME not set")
+	}
+
+	podMetrics, err := rb.metricsClient.MetricsV1beta1().PodMetricses(rb.config.PodNamespace).Get(ctx, rb.config.PodName, metav1.GetOptions{})
+	if err != nil {
+		return SelfUsage{}, fmt.Errorf("failed to get pod metrics: %v", err)
+	}
+
+	usage := SelfUsage{Source: "pod-metrics"}
+	for _, container := range podMetrics.Containers {
+		usage.CPUMillicores += float64(container.Usage.Cpu().MilliValue())
+		usage.MemoryBytes += container.Usage.Memory().Value()
+	}
+	return usage, nil
+}
+
+// getSelfUsage prefers the cgroup and falls back to PodMetrics
+func (rb *ResourceBurner) getSelfUsage(ctx context.Context) (SelfUsage, error) {
+	usage, cgroupErr := rb.cgroupSelfUsage(time.Now())
+	if cgroupErr == nil {
+		return usage, nil
+	}
+
+	usage, podErr := rb.podMetricsSelfUsage(ctx)
+	if podErr == nil {
+		return usage, nil
+	}
+	return SelfUsage{}, fmt.Errorf("cgroup: %v; pod-metrics: %v", cgroupErr, podErr)
+}
+
+// splitUsage derives the foreign workload share of node usage by removing goburn's own
+func splitUsage(node NodeUsage, self SelfUsage) (WorkloadUsage, error) {
+	if node.CPUCapacity <= 0 || node.MemoryCapacity <= 0 {
+		return WorkloadUsage{}, errors.New("node capacity unknown")
+	}
+
+	workload := WorkloadUsage{
+		OwnCPUPercent:    self.CPUMillicores / float64(node.CPUCapacity) * 100,
+		OwnMemoryPercent: float64(self.MemoryBytes) / float64(node.MemoryCapacity) * 100,
+		Source:           self.Source,
+	}
+	// Sources sample at different times, so never report negative foreign usage
+	workload.ForeignCPUPercent = clampPercent(node.CPUPercent - workload.OwnCPUPercent)
+	workload.ForeignMemoryPercent = clampPercent(node.MemoryPercent - workload.OwnMemoryPercent)
+	return workload, nil
+}
+
+func clampPercent(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 100 {
+		return 100
+	}
+	return value
+}
+
+// getWorkloadUsage measures goburn's share of node usage; ok is false when
+// its own usage cannot be measured and only totals are available
+func (rb *ResourceBurner) getWorkloadUsage(ctx context.Context, node NodeUsage) (WorkloadUsage, bool) {
+	self, err := rb.getSelfUsage(ctx)
+	if err != nil {
+		log.Printf("Failed to measure own usage, backoff uses node totals: %v", err)
+		return WorkloadUsage{}, false
+	}
+
+	workload, err := splitUsage(node, self)
+	if err != nil {
+		log.Printf("Failed to derive foreign workload usage: %v", err)
+		return WorkloadUsage{}, false
+	}
+	return workload, true
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// writeCgroupFixture points cgroupRoot at a temporary hierarchy where this
+// process lives in /kubepods/goburn
+func writeCgroupFixture(t *testing.T, cpuStat, memoryCurrent string) string {
+	t.Helper()
+
+	writeProcFixture(t, map[string]string{"self/cgroup": "0::/kubepods/goburn\n"})
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "kubepods", "goburn")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create cgroup fixture: %v", err)
+	}
+	writeCgroupFile(t, dir, "cpu.stat", cpuStat)
+	writeCgroupFile(t, dir, "memory.current", memoryCurrent)
+
+	oldRoot := cgroupRoot
+	cgroupRoot = root
+	t.Cleanup(func() { cgroupRoot = oldRoot })
+	return dir
+}
+
+func writeCgroupFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+func TestSelfUsageTracker(t *testing.T) {
+	var tracker selfUsageTracker
+	start := time.Now()
+
+	if _, ok := tracker.update(1000000, start); ok {
+		t.Error("First sample should not produce a rate")
+	}
+
+	// 1.5s of CPU time over 2s is 750 millicores
+	millicores, ok := tracker.update(2500000, start.Add(2*time.Second))
+	if !ok || millicores != 750 {
+		t.Errorf("update() = %v, %v, want 750, true", millicores, ok)
+	}
+
+	// A restarted cgroup re-baselines
+	if _, ok := tracker.update(10, start.Add(3*time.Second)); ok {
+		t.Error("Counter reset should not produce a rate")
+	}
+}
+
+func TestCgroupSelfUsage(t *testing.T) {
+	dir := writeCgroupFixture(t, "usage_usec 1000000\nuser_usec 800000\nsystem_usec 200000\n", "104857600\n")
+	rb := createTestResourceBurner(t)
+	start := time.Now()
+
+	if _, err := rb.cgroupSelfUsage(start); err == nil {
+		t.Error("Expected error on the first sample, got nil")
+	}
+
+	writeCgroupFile(t, dir, "cpu.stat", "usage_usec 1500000\n")
+	usage, err := rb.cgroupSelfUsage(start.Add(time.Second))
+	if err != nil {
+		t.Fatalf("cgroupSelfUsage() error = %v", err)
+	}
+	if usage.CPUMillicores != 500 {
+		t.Errorf("CPUMillicores = %v, want 500", usage.CPUMillicores)
+	}
+	if usage.MemoryBytes != 100*1024*1024 {
+		t.Errorf("MemoryBytes = %d, want %d", usage.MemoryBytes, 100*1024*1024)
+	}
+	if usage.Source != "cgroup" {
+		t.Errorf("Source = %s, want cgroup", usage.Source)
+	}
+}
+
+func TestCgroupSelfUsage_BurnCgroup(t *testing.T) {
+	// The parent counts the control and burn leaves together
+	parent := writeCgroupFixture(t, "usage_usec 1000000\n", "209715200\n")
+	control := filepath.Join(parent, burnCgroupControl)
+	if err := os.MkdirAll(control, 0o755); err != nil {
+		t.Fatalf("failed to create control cgroup: %v", err)
+	}
+	writeCgroupFile(t, control, "cpu.stat", "usage_usec 100000\n")
+	writeCgroupFile(t, control, "memory.current", "10485760\n")
+	writeProcFixture(t, map[string]string{"self/cgroup": "0::/kubepods/goburn/control\n"})
+
+	rb := createTestResourceBurner(t)
+	start := time.Now()
+	rb.cgroupSelfUsage(start)
+	writeCgroupFile(t, parent, "cpu.stat", "usage_usec 2000000\n")
+	usage, err := rb.cgroupSelfUsage(start.Add(time.Second))
+	if err != nil {
+		t.Fatalf("cgroupSelfUsage() error = %v", err)
+	}
+	if usage.CPUMillicores != 1000 || usage.MemoryBytes != 200*1024*1024 {
+		t.Errorf("usage = %vm, %d bytes, want the parent's 1000m and 200 MB", usage.CPUMillicores, usage.MemoryBytes)
+	}
+}
+
+func TestReadCgroupUsage_Errors(t *testing.T) {
+	dir := writeCgroupFixture(t, "user_usec 5\n", "1\n")
+	if _, _, err := readCgroupUsage(dir); err == nil {
+		t.Error("Expected error without usage_usec, got nil")
+	}
+
+	writeCgroupFile(t, dir, "cpu.stat", "usage_usec 5\n")
+	writeCgroupFile(t, dir, "memory.current", "max\n")
+	if _, _, err := readCgroupUsage(dir); err == nil {
+		t.Error("Expected error for malformed memory.current, got nil")
+	}
+
+	writeProcFixture(t, map[string]string{"self/cgroup": "1:name=systemd:/\n"})
+	if _, err := ownCgroupPath(); err == nil {
+		t.Error("Expected error without a cgroup v2 entry, got nil")
+	}
+}
+
+func TestPodMetricsSelfUsage(t *testing.T) {
+	podMetrics := &metricsv1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "goburn-abc", Namespace: "goburn"},
+		Containers: []metricsv1beta1.ContainerMetrics{
+			{Name: "goburn", Usage: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("250m"),
+				corev1.ResourceMemory: resource.MustParse("64Mi"),
+			}},
+		},
+	}
+
+	// The fake tracker files PodMetrics under a different resource than the
+	// client asks for, so answer the get directly
+	metricsClient := metricsfake.NewSimpleClientset()
+	metricsClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.GetAction).GetName() != podMetrics.Name {
+			return false, nil, nil
+		}
+		return true, podMetrics, nil
+	})
+
+	rb := createTestResourceBurner(t)
+	rb.metricsClient = metricsClient
+	rb.config.PodNamespace = "goburn"
+
+	if _, err := rb.podMetricsSelfUsage(context.Background()); err == nil {
+		t.Error("Expected error without POD_NAME, got nil")
+	}
+
+	rb.config.PodName = "goburn-abc"
+	usage, err := rb.podMetricsSelfUsage(context.Background())
+	if err != nil {
+		t.Fatalf("podMetricsSelfUsage() error = %v", err)
+	}
+	if usage.CPUMillicores != 250 || usage.MemoryBytes != 64*1024*1024 {
+		t.Errorf("usage = %+v, want 250m and 64Mi", usage)
+	}
+}
+
+func TestSplitUsage(t *testing.T) {
+	node := NodeUsage{
+		CPUPercent:     60,
+		MemoryPercent:  50,
+		CPUCapacity:    4000,
+		MemoryCapacity: 8 * 1024 * 1024 * 1024,
+	}
+	self := SelfUsage{CPUMillicores: 1000, MemoryBytes: 2 * 1024 * 1024 * 1024, Source: "cgroup"}
+
+	workload, err := splitUsage(node, self)
+	if err != nil {
+		t.Fatalf("splitUsage() error = %v", err)
+	}
+	if workload.OwnCPUPercent != 25 || workload.ForeignCPUPercent != 35 {
+		t.Errorf("CPU split = own %v / foreign %v, want 25 / 35", workload.OwnCPUPercent, workload.ForeignCPUPercent)
+	}
+	if workload.OwnMemoryPercent != 25 || workload.ForeignMemoryPercent != 25 {
+		t.Errorf("Memory split = own %v / foreign %v, want 25 / 25", workload.OwnMemoryPercent, workload.ForeignMemoryPercent)
+	}
+
+	// Readings taken at different times may put own usage above the total
+	self.CPUMillicores = 3000
+	workload, _ = splitUsage(node, self)
+	if workload.ForeignCPUPercent != 0 {
+		t.Errorf("ForeignCPUPercent = %v, want 0", workload.ForeignCPUPercent)
+	}
+
+	if _, err := splitUsage(NodeUsage{CPUPercent: 50}, self); err == nil {
+		t.Error("Expected error without node capacity, got nil")
+	}
+}
+
+func TestResourceBurner_ReleaseLoad(t *testing.T) {
+	rb := createTestResourceBurner(t)
+	rb.config.MaxMemoryMB = 10
+
+	rb.adjustCPULoad(90.0, 10.0)
+	rb.adjustMemoryLoad(90.0, 10.0)
+	if rb.cpuWorkers == 0 || rb.memory.SizeMB() == 0 {
+		t.Fatalf("Expected load to be added, got %d workers and %d MB", rb.cpuWorkers, rb.memory.SizeMB())
+	}
+
+	rb.releaseCPULoad()
+	rb.releaseMemoryLoad()
+	if rb.cpuWorkers != 0 || len(rb.stopChannels) != 0 {
+		t.Errorf("Expected no CPU workers after release, got %d", rb.cpuWorkers)
+	}
+	if rb.memory.SizeMB() != 0 {
+		t.Errorf("Expected no memory after release, got %d MB", rb.memory.SizeMB())
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sinkReportInterval is how often the sink logs received throughput
+const sinkReportInterval = 10 * time.Second
+
+// httpMethodPrefixes are the request line prefixes that route a TCP
+// connection to the HTTP handler instead of the raw stream sink
+var httpMethodPrefixes = []string{"POST ", "PUT ", "GET ", "HEAD "}
+
+// SinkStats is the received byte count per protocol
+type SinkStats struct {
+	TCPBytes  uint64 `json:"tcp_bytes"`
+	UDPBytes  uint64 `json:"udp_bytes"`
+	HTTPBytes uint64 `json:"http_bytes"`
+}
+
+// Total returns the bytes received over all protocols
+func (s SinkStats) Total() uint64 {
+	return s.TCPBytes + s.UDPBytes + s.HTTPBytes
+}
+
+// trafficSink is the receiving end for generated traffic. It accepts TCP
+// streams, UDP datagrams and HTTP POSTs on the same port and discards them.
+type trafficSink struct {
+	addr string
+
+	tcp      net.Listener
+	udp      net.PacketConn
+	httpConn chan net.Conn
+	server   *http.Server
+	wg       sync.WaitGroup
+	closed   chan struct{}
+
+	tcpBytes  atomic.Uint64
+	udpBytes  atomic.Uint64
+	httpBytes atomic.Uint64
+}
+
+func newTrafficSink(addr string) *trafficSink {
+	return &trafficSink{
+		addr:     addr,
+		httpConn: make(chan net.Conn),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Start binds TCP and UDP on the configured address and begins accepting traffic
+func (s *trafficSink) Start() error {
+	tcp, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on tcp %s: %v", s.addr, err)
+	}
+
+	// Bind UDP to the same port, which matters when the address used port 0
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		tcp.Close()
+		return fmt.Errorf("invalid sink address %q: %v", s.addr, err)
+	}
+	port := tcp.Addr().(*net.TCPAddr).Port
+	udp, err := net.ListenPacket("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		tcp.Close()
+		return fmt.Errorf("failed to listen on udp %s: %v", s.addr, err)
+	}
+
+	s.tcp = tcp
+	s.udp = udp
+	s.server = &http.Server{
+		Handler:           http.HandlerFunc(s.serveHTTP),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	s.wg.Add(3)
+	go s.acceptTCP()
+	go s.readUDP()
+	go func() {
+		defer s.wg.Done()
+		s.server.Serve(&chanListener{conns: s.httpConn, addr: tcp.Addr(), closed: s.closed})
+	}()
+	return nil
+}
+
+// Addr returns the address the sink is listening on
+func (s *trafficSink) Addr() string {
+	if s.tcp == nil {
+		return s.addr
+	}
+	return s.tcp.Addr().String()
+}
+
+// Stats returns the bytes received so far
+func (s *trafficSink) Stats() SinkStats {
+	return SinkStats{
+		TCPBytes:  s.tcpBytes.Load(),
+		UDPBytes:  s.udpBytes.Load(),
+		HTTPBytes: s.httpBytes.Load(),
+	}
+}
+
+// Close stops accepting traffic and waits for the listeners to exit
+func (s *trafficSink) Close() error {
+	select {
+	case <-s.closed:
+		return nil
+	default:
+		close(s.closed)
+	}
+
+	if s.tcp != nil {
+		s.tcp.Close()
+	}
+	if s.udp != nil {
+		s.udp.Close()
+	}
+	if s.server != nil {
+		s.server.Close()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+func (s *trafficSink) acceptTCP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleTCP(conn)
+	}
+}
+
+// handleTCP peeks at the first bytes to tell HTTP requests from raw streams
+func (s *trafficSink) handleTCP(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	prefix, _ := reader.Peek(5)
+	conn.SetReadDeadline(time.Time{})
+
+	if isHTTPPrefix(prefix) {
+		select {
+		case s.httpConn <- &peekedConn{Conn: conn, reader: reader}:
+		case <-s.closed:
+			conn.Close()
+		}
+		return
+	}
+
+	defer conn.Close()
+	io.Copy(&countingDiscard{counter: &s.tcpBytes}, reader)
+}
+
+func isHTTPPrefix(prefix []byte) bool {
+	for _, method := range httpMethodPrefixes {
+		if strings.HasPrefix(string(prefix), method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *trafficSink) readUDP() {
+	defer s.wg.Done()
+
+	buffer := make([]byte, 64*1024)
+	for {
+		n, _, err := s.udp.ReadFrom(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		s.udpBytes.Add(uint64(n))
+	}
+}
+
+// serveHTTP discards POST and PUT bodies and answers GET with the byte counters
+func (s *trafficSink) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut:
+		io.Copy(&countingDiscard{counter: &s.httpBytes}, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Stats())
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// report logs the received throughput every interval until ctx is done
+func (s *trafficSink) report(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	prev := s.Stats()
+	prevTime := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			cur := s.Stats()
+			seconds := now.Sub(prevTime).Seconds()
+			log.Printf("📥 Sink received - TCP: %.1f Mbps, UDP: %.1f Mbps, HTTP: %.1f Mbps",
+				bytesToMbps(cur.TCPBytes-prev.TCPBytes, seconds),
+				bytesToMbps(cur.UDPBytes-prev.UDPBytes, seconds),
+				bytesToMbps(cur.HTTPBytes-prev.HTTPBytes, seconds))
+			prev, prevTime = cur, now
+		}
+	}
+}
+
+func bytesToMbps(bytes uint64, seconds float64) float64 {
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / seconds / 1e6
+}
+
+// runSink runs the sink alone, without any burning, until ctx is done
+func runSink(ctx context.Context, addr string, reportInterval time.Duration) error {
+	sink := newTrafficSink(addr)
+	if err := sink.Start(); err != nil {
+		return err
+	}
+	defer sink.Close()
+
+	log.Printf("📥 Traffic sink listening on %s (tcp, udp, http)", sink.Addr())
+	sink.report(ctx, reportInterval)
+	return nil
+}
+
+// countingDiscard drops everything written to it and counts the bytes
+type countingDiscard struct {
+	counter *atomic.Uint64
+}
+
+func (c *countingDiscard) Write(p []byte) (int, error) {
+	c.counter.Add(uint64(len(p)))
+	return len(p), nil
+}
+
+// peekedConn replays the bytes consumed while sniffing the protocol
+type peekedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// chanListener hands connections accepted elsewhere to an http.Server
+type chanListener struct {
+	conns  chan net.Conn
+	addr   net.Addr
+	closed chan struct{}
+}
+
+func (l *chanListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *chanListener) Close() error {
+	return nil
+}
+
+func (l *chanListener) Addr() net.Addr {
+	return l.addr
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// startTestSink starts a sink on a random loopback port
+func startTestSink(t *testing.T) *trafficSink {
+	t.Helper()
+
+	sink := newTrafficSink("127.0.0.1:0")
+	if err := sink.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+// waitForBytes polls until the counter reaches want or the deadline passes
+func waitForBytes(t *testing.T, get func() uint64, want uint64) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if get() >= want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("received %d bytes, want %d", get(), want)
+}
+
+func TestTrafficSink_TCP(t *testing.T) {
+	sink := startTestSink(t)
+
+	conn, err := net.Dial("tcp", sink.Addr())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	payload := bytes.Repeat([]byte{0xAB}, 100*1024)
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	waitForBytes(t, func() uint64 { return sink.Stats().TCPBytes }, uint64(len(payload)))
+	if sink.Stats().HTTPBytes != 0 {
+		t.Errorf("HTTPBytes = %d, want 0 for a raw stream", sink.Stats().HTTPBytes)
+	}
+}
+
+func TestTrafficSink_UDP(t *testing.T) {
+	sink := startTestSink(t)
+
+	conn, err := net.Dial("udp", sink.Addr())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	for i := 0; i < 10; i++ {
+		if _, err := conn.Write(make([]byte, udpPayloadBytes)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+
+	// Loopback UDP does not drop at this volume
+	waitForBytes(t, func() uint64 { return sink.Stats().UDPBytes }, 10*udpPayloadBytes)
+}
+
+func TestTrafficSink_HTTP(t *testing.T) {
+	sink := startTestSink(t)
+	url := "http://" + sink.Addr() + "/"
+
+	payload := bytes.Repeat([]byte("x"), 64*1024)
+	resp, err := http.Post(url, "application/octet-stream", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST status = %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+
+	resp, err = http.Get(url)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	var stats SinkStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatalf("failed to decode stats: %v", err)
+	}
+	if stats.HTTPBytes != uint64(len(payload)) {
+		t.Errorf("HTTPBytes = %d, want %d", stats.HTTPBytes, len(payload))
+	}
+	if stats.TCPBytes != 0 {
+		t.Errorf("TCPBytes = %d, want 0 for HTTP requests", stats.TCPBytes)
+	}
+}
+
+func TestTrafficSink_FromTrafficGenerator(t *testing.T) {
+	if _, err := interfaceAddr("lo", false); err != nil {
+		t.Skipf("loopback interface not available: %v", err)
+	}
+	sink := startTestSink(t)
+
+	for _, mode := range []string{NetworkModeTCP, NetworkModeUDP} {
+		g := newTrafficGenerator(Config{
+			NetworkMode:      mode,
+			NetworkInterface: "lo",
+			NetworkTarget:    sink.Addr(),
+			NetworkStreams:   1,
+		})
+		g.SetRate(8)
+		time.Sleep(200 * time.Millisecond)
+		g.Stop()
+	}
+
+	stats := sink.Stats()
+	if stats.TCPBytes == 0 || stats.UDPBytes == 0 {
+		t.Errorf("Expected the sink to receive both TCP and UDP traffic, got %+v", stats)
+	}
+	if stats.Total() != stats.TCPBytes+stats.UDPBytes+stats.HTTPBytes {
+		t.Errorf("Total() = %d, want the sum of %+v", stats.Total(), stats)
+	}
+}
+
+func TestTrafficSink_InvalidAddr(t *testing.T) {
+	sink := newTrafficSink("not-an-address")
+	if err := sink.Start(); err == nil {
+		sink.Close()
+		t.Error("Expected error for invalid address, got nil")
+	}
+}
+
+func TestIsHTTPPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected bool
+	}{
+		{"POST ", true},
+		{"GET /", true},
+		{"PUT /", true},
+		{"\xab\xab\xab\xab\xab", false},
+		{"POS", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if result := isHTTPPrefix([]byte(tt.prefix)); result != tt.expected {
+			t.Errorf("isHTTPPrefix(%q) = %v, want %v", tt.prefix, result, tt.expected)
+		}
+	}
+}
+
+func TestBytesToMbps(t *testing.T) {
+	if result := bytesToMbps(1250000, 1); result != 10 {
+		t.Errorf("bytesToMbps(1250000, 1) = %v, want 10", result)
+	}
+	if result := bytesToMbps(100, 0); result != 0 {
+		t.Errorf("bytesToMbps(100, 0) = %v, want 0", result)
+	}
+}
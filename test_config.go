@@ -32,6 +32,7 @@ func GetTestConfigs() []TestConfig {
 				NodeName:                  "test-node",
 				EnableMemoryUtilization:   true,
 				NetworkInterface:          "eth0",
+				MaxNetworkMbps:            1000,
 			},
 			Description: "Default configuration with all features enabled",
 		},
@@ -50,6 +51,7 @@ func GetTestConfigs() []TestConfig {
 				NodeName:                  "amd64-node",
 				EnableMemoryUtilization:   false, // Disabled for AMD64
 				NetworkInterface:          "eth0",
+				MaxNetworkMbps:            1000,
 			},
 			Description: "AMD64 node configuration (CPU + Network only)",
 		},
@@ -68,6 +70,7 @@ func GetTestConfigs() []TestConfig {
 				NodeName:                  "arm64-node",
 				EnableMemoryUtilization:   true, // Enabled for ARM64
 				NetworkInterface:          "eth0",
+				MaxNetworkMbps:            1000,
 			},
 			Description: "ARM64 node configuration (CPU + Network + Memory)",
 		},
@@ -86,6 +89,7 @@ func GetTestConfigs() []TestConfig {
 				NodeName:                  "aggressive-node",
 				EnableMemoryUtilization:   true,
 				NetworkInterface:          "eth0",
+				MaxNetworkMbps:            1000,
 			},
 			Description: "Aggressive configuration for maximum utilization",
 		},
@@ -104,6 +108,7 @@ func GetTestConfigs() []TestConfig {
 				NodeName:                  "conservative-node",
 				EnableMemoryUtilization:   true,
 				NetworkInterface:          "eth0",
+				MaxNetworkMbps:            1000,
 			},
 			Description: "Conservative configuration for production environments",
 		},
@@ -122,6 +127,7 @@ func GetTestConfigs() []TestConfig {
 				NodeName:                  "memory-disabled-node",
 				EnableMemoryUtilization:   false,
 				NetworkInterface:          "eth0",
+				MaxNetworkMbps:            1000,
 			},
 			Description: "Configuration with memory utilization disabled",
 		},
@@ -134,15 +140,14 @@ func CreateTestResourceBurnerWithConfig(config Config) *ResourceBurner {
 	metricsClient := metricsfake.NewSimpleClientset()
 
 	return &ResourceBurner{
-		config:           config,
-		k8sClient:        k8sClient,
-		metricsClient:    metricsClient,
-		memoryData:       make([]byte, 0),
-		cpuWorkers:       0,
-		stopChannels:     make([]chan bool, 0),
-		networkWorkers:   0,
-		networkStopChans: make([]chan bool, 0),
-		cpuSamples:       make([]float64, 0),
+		config:        config,
+		k8sClient:     k8sClient,
+		metricsClient: metricsClient,
+		metricsSource: newMetricsServerSource(k8sClient, metricsClient, config.NodeName),
+		cpuWorkers:    0,
+		stopChannels:  make([]chan bool, 0),
+		traffic:       newTrafficGenerator(config),
+		cpuSamples:    newPercentileWindow(config.PercentileWindow, config.CPUPercentile, config.MonitorInterval),
 	}
 }
 